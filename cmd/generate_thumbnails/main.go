@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Packs frames extracted from a title's video, or an episode's, into sprite
+// sheets with a WebVTT thumbnails track for scrubbing previews. The files are
+// stored in media storage (see STORAGE_BACKEND) and replace any earlier
+// thumbnails for the same title or episode. Frames are taken in name order,
+// one every -interval seconds:
+//
+//	ffmpeg -i fight-club.mp4 -vf fps=1/10 ./frames/fight-club/frame-%05d.jpg
+//	go run ./cmd/generate_thumbnails -tmdb-id 550 -dir ./frames/fight-club -interval 10
+//	go run ./cmd/generate_thumbnails -tmdb-id 1396 -season 1 -episode 3 -dir ./frames/bb-s01e03
+func main() {
+	tmdbID := flag.Int("tmdb-id", 0, "TMDB ID of the media")
+	season := flag.Int("season", -1, "season number, for an episode of a show")
+	episode := flag.Int("episode", 0, "episode number, for an episode of a show")
+	dir := flag.String("dir", "", "directory with the extracted frames")
+	interval := flag.Float64("interval", 10, "seconds between frames")
+	width := flag.Int("width", 160, "width of each thumbnail in pixels")
+	columns := flag.Int("columns", 10, "thumbnails across each sprite sheet")
+	rows := flag.Int("rows", 10, "thumbnails down each sprite sheet")
+	flag.Parse()
+
+	if *tmdbID <= 0 || *dir == "" || *interval <= 0 || *width <= 0 || *columns <= 0 || *rows <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	mediaCollection := database.OpenCollection("media")
+	var media models.Media
+	if err := mediaCollection.FindOne(ctx, bson.M{"tmdb_id": *tmdbID}).Decode(&media); err != nil {
+		log.Fatalf("finding media %d: %v", *tmdbID, err)
+	}
+
+	filter := bson.M{"tmdb_id": *tmdbID, "season": nil, "episode": nil}
+	setOnInsert := bson.M{"track_id": bson.NewObjectID().Hex(), "tmdb_id": *tmdbID}
+	baseDir := path.Join("thumbnails", fmt.Sprint(*tmdbID))
+	var seasonPtr, episodePtr *int
+	switch {
+	case media.MediaType == models.MediaTypeTV:
+		if *season < 0 || *episode < 1 {
+			log.Fatalf("%s is a show: -season and -episode are required", media.Title)
+		}
+		seasonPtr, episodePtr = season, episode
+		filter["season"], filter["episode"] = *season, *episode
+		setOnInsert["season"], setOnInsert["episode"] = *season, *episode
+		baseDir = path.Join(baseDir, fmt.Sprintf("s%02de%02d", *season, *episode))
+	case *season >= 0 || *episode > 0:
+		log.Fatalf("%s is a movie: -season and -episode don't apply", media.Title)
+	}
+	// A fresh directory each time, so players never mix old and new sprites
+	baseDir = path.Join(baseDir, bson.NewObjectID().Hex())
+
+	frames, err := services.ListFrames(*dir)
+	if err != nil {
+		log.Fatalf("reading frames: %v", err)
+	}
+	layout := services.SpriteLayout{
+		Interval:  time.Duration(*interval * float64(time.Second)),
+		TileWidth: *width,
+		Columns:   *columns,
+		Rows:      *rows,
+	}
+	sprites, err := services.GenerateSprites(frames, layout)
+	if err != nil {
+		log.Fatalf("generating sprites: %v", err)
+	}
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, sheet := range sprites.Sheets {
+		if err = storage.Put(ctx, path.Join(baseDir, sheet.Name), bytes.NewReader(sheet.Data), int64(len(sheet.Data))); err != nil {
+			log.Fatalf("storing %s: %v", sheet.Name, err)
+		}
+	}
+	if err = storage.Put(ctx, path.Join(baseDir, services.ThumbnailsFile), bytes.NewReader(sprites.VTT), int64(len(sprites.VTT))); err != nil {
+		log.Fatalf("storing %s: %v", services.ThumbnailsFile, err)
+	}
+
+	now := time.Now()
+	setOnInsert["created_at"] = now
+	update := bson.M{
+		"$set": bson.M{
+			"base_dir":     baseDir,
+			"interval":     *interval,
+			"tile_width":   *width,
+			"tile_height":  sprites.TileHeight,
+			"columns":      *columns,
+			"rows":         *rows,
+			"frame_count":  sprites.FrameCount,
+			"sprite_count": len(sprites.Sheets),
+			"updated_at":   now,
+		},
+		"$setOnInsert": setOnInsert,
+	}
+	var previous models.ThumbnailTrack
+	err = database.OpenCollection("thumbnail_tracks").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)).Decode(&previous)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Fatalf("registering thumbnails: %v", err)
+	}
+
+	if previous.BaseDir != "" && previous.BaseDir != baseDir {
+		if err = services.DeletePrefix(ctx, storage, previous.BaseDir); err != nil {
+			log.Printf("removing previous thumbnails: %v", err)
+		}
+	}
+
+	log.Printf("%s: %d frames in %d sprite sheets registered at %s", media.Title, sprites.FrameCount,
+		len(sprites.Sheets), services.ThumbnailsPath(*tmdbID, seasonPtr, episodePtr))
+}
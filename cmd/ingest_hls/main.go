@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Registers a directory of pre-encoded HLS renditions against a title, or an
+// episode of a show. The directory must hold a manifest.json listing the
+// renditions; it is copied into media storage (see STORAGE_BACKEND) and
+// replaces any earlier renditions for the same title or episode. The title's
+// video_url and sources are left alone, so players without HLS can still
+// fall back on them.
+//
+//	go run ./cmd/ingest_hls -tmdb-id 550 -dir ./encoded/fight-club
+//	go run ./cmd/ingest_hls -tmdb-id 1396 -season 1 -episode 3 -dir ./encoded/bb-s01e03
+func main() {
+	tmdbID := flag.Int("tmdb-id", 0, "TMDB ID of the media")
+	season := flag.Int("season", -1, "season number, for an episode of a show")
+	episode := flag.Int("episode", 0, "episode number, for an episode of a show")
+	dir := flag.String("dir", "", "directory with manifest.json and the renditions")
+	flag.Parse()
+
+	if *tmdbID <= 0 || *dir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	renditions, err := services.LoadHLSManifest(*dir)
+	if err != nil {
+		log.Fatalf("reading manifest: %v", err)
+	}
+
+	mediaCollection := database.OpenCollection("media")
+	var media models.Media
+	if err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": *tmdbID}).Decode(&media); err != nil {
+		log.Fatalf("finding media %d: %v", *tmdbID, err)
+	}
+
+	filter := bson.M{"tmdb_id": *tmdbID, "season": nil, "episode": nil}
+	setOnInsert := bson.M{"asset_id": bson.NewObjectID().Hex(), "tmdb_id": *tmdbID}
+	baseDir := path.Join("hls", fmt.Sprint(*tmdbID))
+	var seasonPtr, episodePtr *int
+	switch {
+	case media.MediaType == models.MediaTypeTV:
+		if *season < 0 || *episode < 1 {
+			log.Fatalf("%s is a show: -season and -episode are required", media.Title)
+		}
+		if media.NumberOfSeasons > 0 && *season > media.NumberOfSeasons {
+			log.Fatalf("%s has %d seasons", media.Title, media.NumberOfSeasons)
+		}
+		seasonPtr, episodePtr = season, episode
+		filter["season"], filter["episode"] = *season, *episode
+		setOnInsert["season"], setOnInsert["episode"] = *season, *episode
+		baseDir = path.Join(baseDir, fmt.Sprintf("s%02de%02d", *season, *episode))
+	case *season >= 0 || *episode > 0:
+		log.Fatalf("%s is a movie: -season and -episode don't apply", media.Title)
+	}
+	// Every ingest gets a fresh directory so players mid-stream keep
+	// working until the asset points at the new files
+	baseDir = path.Join(baseDir, bson.NewObjectID().Hex())
+
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = uploadTree(ctx, storage, *dir, baseDir); err != nil {
+		log.Fatalf("copying renditions: %v", err)
+	}
+
+	now := time.Now()
+	setOnInsert["created_at"] = now
+	update := bson.M{
+		"$set":         bson.M{"base_dir": baseDir, "renditions": renditions, "updated_at": now},
+		"$setOnInsert": setOnInsert,
+	}
+	var previous models.VideoAsset
+	err = database.OpenCollection("video_assets").FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)).Decode(&previous)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Fatalf("registering renditions: %v", err)
+	}
+
+	if previous.BaseDir != "" && previous.BaseDir != baseDir {
+		if err = services.DeletePrefix(ctx, storage, previous.BaseDir); err != nil {
+			log.Printf("removing previous renditions: %v", err)
+		}
+	}
+
+	masterURL := services.HLSMasterPath(*tmdbID, seasonPtr, episodePtr)
+	log.Printf("%s: %d renditions registered at %s", media.Title, len(renditions), masterURL)
+}
+
+// uploadTree stores the regular files under src at the same paths below
+// prefix, leaving out the manifest
+func uploadTree(ctx context.Context, storage services.Storage, src, prefix string) error {
+	return filepath.WalkDir(src, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || rel == services.HLSManifestFile {
+			return nil
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		return storage.Put(ctx, path.Join(prefix, filepath.ToSlash(rel)), file, info.Size())
+	})
+}
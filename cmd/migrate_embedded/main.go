@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// embeddedTitle is the pre-split shape of media and movie documents
+type embeddedTitle struct {
+	ID       bson.ObjectID    `bson:"_id"`
+	TMDBID   int              `bson:"tmdb_id"`
+	ImdbID   string           `bson:"imdb_id"`
+	Reviews  []models.Review  `bson:"reviews"`
+	Comments []models.Comment `bson:"comments"`
+	Ratings  []models.Rating  `bson:"ratings"`
+}
+
+// embeddedWrite copies one embedded item into its own collection
+type embeddedWrite struct {
+	// filter finds the item if it has been copied already
+	filter bson.M
+	update bson.M
+	// unique is another unique key the item must not collide with, if any
+	unique bson.M
+	// document is kept in the conflicts collection if the copy fails
+	document any
+}
+
+// migrationCounts tallies what happened to the embedded items of one collection
+type migrationCounts struct {
+	copied, existing, conflicts int
+}
+
+// Moves reviews, comments and ratings embedded in media and movie documents
+// into their own collections. Safe to run more than once.
+//
+// An item that collides with one already stored, such as a second review by
+// the same user, is kept in <collection>_migration_conflicts (for example
+// media_reviews_migration_conflicts) before the title's embedded arrays are
+// removed. With -dry-run nothing is written; the counts are only reported.
+//
+//	go run ./cmd/migrate_embedded -dry-run
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report what would be migrated without writing anything")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if !*dryRun {
+		if err := database.EnsureIndexes(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	media := database.OpenCollection("media")
+	movies := database.OpenCollection("movies")
+
+	if err := migrate(ctx, media, "tmdb_id", database.OpenCollection("media_reviews"), database.OpenCollection("media_comments"), database.OpenCollection("media_ratings"), *dryRun); err != nil {
+		log.Fatalf("migrating media: %v", err)
+	}
+	if err := migrate(ctx, movies, "imdb_id", database.OpenCollection("movie_reviews"), nil, database.OpenCollection("movie_ratings"), *dryRun); err != nil {
+		log.Fatalf("migrating movies: %v", err)
+	}
+
+	if *dryRun {
+		log.Println("dry run complete, nothing was written")
+		return
+	}
+
+	if err := services.NewRatingService(database.OpenCollection("media_ratings"), media, "tmdb_id").Recompute(ctx); err != nil {
+		log.Fatalf("recomputing media ratings: %v", err)
+	}
+	if err := services.NewRatingService(database.OpenCollection("movie_ratings"), movies, "imdb_id").Recompute(ctx); err != nil {
+		log.Fatalf("recomputing movie ratings: %v", err)
+	}
+
+	log.Println("migration complete")
+}
+
+func migrate(ctx context.Context, titles *mongo.Collection, key string, reviews, comments, ratings *mongo.Collection, dryRun bool) error {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"reviews": bson.M{"$exists": true}},
+		bson.M{"comments": bson.M{"$exists": true}},
+		bson.M{"ratings": bson.M{"$exists": true}},
+	}}
+
+	cursor, err := titles.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[*mongo.Collection]*migrationCounts{reviews: {}, ratings: {}}
+	if comments != nil {
+		counts[comments] = &migrationCounts{}
+	}
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var title embeddedTitle
+		if err := cursor.Decode(&title); err != nil {
+			return err
+		}
+
+		var titleID any = title.TMDBID
+		if key == "imdb_id" {
+			titleID = title.ImdbID
+		}
+
+		var reviewWrites []embeddedWrite
+		for _, review := range title.Reviews {
+			review.TMDBID, review.ImdbID = 0, ""
+			if key == "imdb_id" {
+				review.ImdbID = title.ImdbID
+			} else {
+				review.TMDBID = title.TMDBID
+			}
+			reviewWrites = append(reviewWrites, embeddedWrite{
+				filter:   bson.M{"review_id": review.ReviewID},
+				update:   bson.M{"$setOnInsert": review},
+				unique:   bson.M{key: titleID, "user_id": review.UserID},
+				document: review,
+			})
+		}
+
+		var commentWrites []embeddedWrite
+		for _, comment := range title.Comments {
+			comment.TMDBID = title.TMDBID
+			commentWrites = append(commentWrites, embeddedWrite{
+				filter:   bson.M{"comment_id": comment.CommentID},
+				update:   bson.M{"$setOnInsert": comment},
+				document: comment,
+			})
+		}
+
+		var ratingWrites []embeddedWrite
+		for _, rating := range title.Ratings {
+			ratingWrites = append(ratingWrites, embeddedWrite{
+				filter: bson.M{key: titleID, "user_id": rating.UserID},
+				update: bson.M{"$setOnInsert": bson.M{
+					"rating":     rating.Rating,
+					"created_at": rating.CreatedAt,
+					"updated_at": rating.CreatedAt,
+				}},
+				document: bson.M{key: titleID, "user_id": rating.UserID, "rating": rating.Rating, "created_at": rating.CreatedAt},
+			})
+		}
+
+		copyWrites := copyEmbedded
+		if dryRun {
+			copyWrites = planEmbedded
+		}
+
+		if err := copyWrites(ctx, reviews, reviewWrites, counts[reviews]); err != nil {
+			return err
+		}
+		if comments != nil {
+			if err := copyWrites(ctx, comments, commentWrites, counts[comments]); err != nil {
+				return err
+			}
+		}
+		if err := copyWrites(ctx, ratings, ratingWrites, counts[ratings]); err != nil {
+			return err
+		}
+
+		// Every item now has a counterpart or a copy in a conflicts collection
+		if !dryRun {
+			unset := bson.M{"$unset": bson.M{"reviews": "", "comments": "", "ratings": ""}}
+			if _, err := titles.UpdateByID(ctx, title.ID, unset); err != nil {
+				return err
+			}
+		}
+		migrated++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	verb := "migrated"
+	if dryRun {
+		verb = "would migrate"
+	}
+	log.Printf("%s: %s %d documents", titles.Name(), verb, migrated)
+	for collection, count := range counts {
+		log.Printf("%s: %d copied, %d already present, %d conflicts", collection.Name(), count.copied, count.existing, count.conflicts)
+	}
+	return nil
+}
+
+// copyEmbedded runs the writes unordered. An item that hits a duplicate key,
+// such as a second embedded review by the same user, is stored in the
+// collection's _migration_conflicts collection instead; any other error is
+// returned.
+func copyEmbedded(ctx context.Context, collection *mongo.Collection, writes []embeddedWrite, counts *migrationCounts) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	operations := make([]mongo.WriteModel, len(writes))
+	for i, write := range writes {
+		operations[i] = mongo.NewUpdateOneModel().SetFilter(write.filter).SetUpdate(write.update).SetUpsert(true)
+	}
+	result, err := collection.BulkWrite(ctx, operations, options.BulkWrite().SetOrdered(false))
+
+	var bulkErr mongo.BulkWriteException
+	if err != nil && !errors.As(err, &bulkErr) {
+		return err
+	}
+
+	var conflicts []any
+	remaining := bulkErr
+	remaining.WriteErrors = nil
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			remaining.WriteErrors = append(remaining.WriteErrors, writeErr)
+			continue
+		}
+		conflicts = append(conflicts, bson.M{
+			"document":    writes[writeErr.Index].document,
+			"error":       writeErr.Message,
+			"archived_at": time.Now(),
+		})
+	}
+	if len(remaining.WriteErrors) > 0 || remaining.WriteConcernError != nil {
+		return remaining
+	}
+
+	if len(conflicts) > 0 {
+		archive := database.OpenCollection(collection.Name() + "_migration_conflicts")
+		if _, err := archive.InsertMany(ctx, conflicts); err != nil {
+			return err
+		}
+		log.Printf("%s: kept %d conflicting items in %s", collection.Name(), len(conflicts), archive.Name())
+	}
+
+	copied := 0
+	if result != nil {
+		copied = int(result.UpsertedCount)
+	}
+	counts.copied += copied
+	counts.conflicts += len(conflicts)
+	counts.existing += len(writes) - copied - len(conflicts)
+	return nil
+}
+
+// planEmbedded counts what copyEmbedded would do without writing anything
+func planEmbedded(ctx context.Context, collection *mongo.Collection, writes []embeddedWrite, counts *migrationCounts) error {
+	var planned []bson.M
+	for _, write := range writes {
+		existing, err := collection.CountDocuments(ctx, write.filter)
+		if err != nil {
+			return err
+		}
+		if existing > 0 || matchesAny(planned, write.filter) {
+			counts.existing++
+			continue
+		}
+
+		if write.unique != nil {
+			colliding, err := collection.CountDocuments(ctx, write.unique)
+			if err != nil {
+				return err
+			}
+			if colliding > 0 || matchesAny(planned, write.unique) {
+				counts.conflicts++
+				continue
+			}
+			planned = append(planned, write.unique)
+		}
+
+		planned = append(planned, write.filter)
+		counts.copied++
+	}
+	return nil
+}
+
+// matchesAny reports whether filter equals one of the keys planned in this batch
+func matchesAny(planned []bson.M, filter bson.M) bool {
+	for _, keys := range planned {
+		if len(keys) != len(filter) {
+			continue
+		}
+		same := true
+		for field, value := range filter {
+			if keys[field] != value {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// legacyTitle is the part of a media document from before video sources
+type legacyTitle struct {
+	ID        bson.ObjectID `bson:"_id"`
+	VideoURL  string        `bson:"video_url"`
+	VideoFile string        `bson:"video_file"`
+}
+
+// Turns the single video_url and video_file of media documents into video
+// sources, the file being the default. The old fields are kept for clients
+// and playback URLs that still use them. Safe to run more than once.
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	media := database.OpenCollection("media")
+	filter := bson.M{
+		"sources": nil,
+		"$or": bson.A{
+			bson.M{"video_url": bson.M{"$nin": bson.A{"", nil}}},
+			bson.M{"video_file": bson.M{"$nin": bson.A{"", nil}}},
+		},
+	}
+
+	cursor, err := media.Find(ctx, filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated, skipped := 0, 0
+	for cursor.Next(ctx) {
+		var title legacyTitle
+		if err := cursor.Decode(&title); err != nil {
+			log.Fatal(err)
+		}
+
+		sources := services.DefaultSources(title.VideoURL, title.VideoFile)
+		if len(sources) == 0 {
+			// Only an HLS master path, which is served from its renditions
+			skipped++
+			continue
+		}
+
+		// Matching on the missing sources again leaves titles given sources
+		// since the scan alone
+		update := bson.M{"$set": bson.M{"sources": sources}}
+		if _, err := media.UpdateOne(ctx, bson.M{"_id": title.ID, "sources": nil}, update); err != nil {
+			log.Fatalf("migrating %s: %v", title.ID.Hex(), err)
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("media: migrated %d documents, skipped %d without a playable video", migrated, skipped)
+}
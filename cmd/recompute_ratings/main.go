@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/services"
+)
+
+// Recomputes average_rating, total_ratings and rating_sum of every media and
+// movie document from the raw ratings collections
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	ratingServices := map[string]*services.RatingService{
+		"media":  services.NewRatingService(database.OpenCollection("media_ratings"), database.OpenCollection("media"), "tmdb_id"),
+		"movies": services.NewRatingService(database.OpenCollection("movie_ratings"), database.OpenCollection("movies"), "imdb_id"),
+	}
+
+	for name, service := range ratingServices {
+		if err := service.Recompute(ctx); err != nil {
+			log.Fatalf("recomputing %s ratings: %v", name, err)
+		}
+		log.Printf("%s: ratings recomputed", name)
+	}
+}
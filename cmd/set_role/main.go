@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Sets the role of the user with the given email. Sign-up always makes plain
+// users, so this is how the first admin is made; after that admins can use
+// PUT /admin/users/:user_id/role.
+//
+//	go run ./cmd/set_role -email admin@example.com -role ADMIN
+func main() {
+	email := flag.String("email", "", "email of the user")
+	role := flag.String("role", "", "ADMIN, MODERATOR or USER")
+	flag.Parse()
+
+	if *email == "" || (*role != "ADMIN" && *role != "MODERATOR" && *role != "USER") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"role": *role, "updated_at": time.Now()}}
+	result, err := database.OpenCollection("users").UpdateOne(ctx, bson.M{"email": *email}, update)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if result.MatchedCount == 0 {
+		log.Fatalf("no user with email %s", *email)
+	}
+
+	log.Printf("%s is now %s", *email, *role)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// artworkSizes are the TMDB image sizes that can be requested for each kind
+// of artwork; the first is the default
+var artworkSizes = map[string][]string{
+	"poster":   {"w500", "w92", "w154", "w185", "w342", "w780", "original"},
+	"backdrop": {"w1280", "w300", "w780", "original"},
+}
+
+// artworkExtensions are the image types TMDB serves
+var artworkExtensions = map[string]bool{".jpg": true, ".png": true, ".svg": true}
+
+// GetMediaArtwork serves a title's poster or backdrop from media storage,
+// fetching it from TMDB the first time it is asked for. ?size= picks a TMDB
+// size such as w342.
+func GetMediaArtwork() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		kind := c.Param("kind")
+		sizes, ok := artworkSizes[kind]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "artwork must be poster or backdrop"})
+			return
+		}
+		size := c.DefaultQuery("size", sizes[0])
+		found := false
+		for _, allowed := range sizes {
+			found = found || allowed == size
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported size"})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		// Media stores full TMDB URLs such as https://image.tmdb.org/t/p/w500/x.jpg;
+		// the file name is the TMDB image path for any size
+		stored := media.PosterPath
+		if kind == "backdrop" {
+			stored = media.BackdropPath
+		}
+		name := path.Base(stored)
+		imagePath := "/" + name
+		if stored == "" || !artworkExtensions[path.Ext(name)] {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This media has no " + kind})
+			return
+		}
+
+		// TMDB gives new artwork a new file name, so cached files never go stale
+		key := path.Join("artwork", tmdbIDStr, size, name)
+		_, err = mediaStorage.Stat(ctx, key)
+		if errors.Is(err, services.ErrObjectNotFound) {
+			var image []byte
+			image, err = mediaTmdbService.FetchImage(size, imagePath)
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+				return
+			}
+			err = mediaStorage.Put(ctx, key, bytes.NewReader(image), int64(len(image)))
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		serveStoredObject(c, key)
+	}
+}
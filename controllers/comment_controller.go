@@ -0,0 +1,622 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// maxCommentDepth is how deep replies may nest; top-level comments are depth 0
+	maxCommentDepth = 3
+	// defaultRepliesPreview is how many replies each thread shows in the comment listing
+	defaultRepliesPreview = 3
+)
+
+func AddMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var commentRequest models.AddCommentRequest
+		if err := c.ShouldBindJSON(&commentRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&commentRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var user models.User
+		userCollection := database.OpenCollection("users")
+		err = userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not find user"})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if commentRequest.Season != nil {
+			if media.MediaType != models.MediaTypeTV {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Season and episode can only be set on TV comments"})
+				return
+			}
+			if media.NumberOfSeasons > 0 && *commentRequest.Season > media.NumberOfSeasons {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Season is out of range"})
+				return
+			}
+		}
+
+		screened, ok := screenContent(ctx, c, userID.(string), commentRequest.Content, minCommentLength)
+		if !ok {
+			return
+		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
+
+		comment := models.Comment{
+			CommentID:     bson.NewObjectID().Hex(),
+			TMDBID:        tmdbID,
+			UserID:        userID.(string),
+			UserName:      user.FirstName + " " + user.LastName,
+			Content:       text,
+			Hidden:        screened.Verdict == services.VerdictHold,
+			Spoiler:       commentRequest.Spoiler,
+			SpoilerRanges: spoilerRanges,
+			Season:        commentRequest.Season,
+			Episode:       commentRequest.Episode,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}
+
+		parentAuthorID := ""
+		if commentRequest.ParentCommentID != "" {
+			var parent models.Comment
+			err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": commentRequest.ParentCommentID}).Decode(&parent)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Parent comment not found"})
+				return
+			}
+			if parent.Deleted {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot reply to a deleted comment"})
+				return
+			}
+			if parent.Depth >= maxCommentDepth {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Maximum reply depth reached"})
+				return
+			}
+			if !checkNotBlocked(ctx, c, userID.(string), parent.UserID, "You cannot reply to this user") {
+				return
+			}
+
+			comment.ParentCommentID = parent.CommentID
+			comment.RootCommentID = parent.RootCommentID
+			if comment.RootCommentID == "" {
+				comment.RootCommentID = parent.CommentID
+			}
+			comment.Depth = parent.Depth + 1
+			parentAuthorID = parent.UserID
+
+			// Replies are about the same episode as the comment they answer unless they say otherwise
+			if comment.Season == nil {
+				comment.Season, comment.Episode = parent.Season, parent.Episode
+			}
+
+			// Count the reply before inserting it so the parent can't be hard-deleted underneath it
+			_, err = mediaCommentCollection.UpdateOne(ctx, bson.M{"comment_id": parent.CommentID}, bson.M{"$inc": bson.M{"reply_count": 1}})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		result, err := mediaCommentCollection.InsertOne(ctx, comment)
+		if err != nil {
+			if comment.ParentCommentID != "" {
+				mediaCommentCollection.UpdateOne(ctx, bson.M{"comment_id": comment.ParentCommentID}, bson.M{"$inc": bson.M{"reply_count": -1}})
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = holdForReview(ctx, screened, "media_comment", comment.CommentID, userID.(string), true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Held comments stay quiet until a moderator approves them
+		if !comment.Hidden {
+			announceComment(comment, parentAuthorID)
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message":         "Comment added successfully",
+			"comment":         comment,
+			"result":          result,
+			"held_for_review": comment.Hidden,
+		})
+	}
+}
+
+// announceComment publishes a new comment to everyone watching the title and,
+// for a reply, notifies the author of the comment it answers
+func announceComment(comment models.Comment, parentAuthorID string) {
+	publishMediaEvent(comment.TMDBID, "comment.created", comment.UserID, comment)
+
+	if parentAuthorID != "" {
+		notify(services.NotificationEvent{
+			Type:       models.NotificationCommentReply,
+			ActorID:    comment.UserID,
+			Recipients: []string{parentAuthorID},
+			TargetType: "media_comment",
+			TargetID:   comment.CommentID,
+			TMDBID:     comment.TMDBID,
+		})
+	}
+}
+
+func UpdateMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		commentID := c.Param("comment_id")
+		if commentID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "comment_id is required"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		role, _ := c.Get("role")
+
+		var updateRequest models.UpdateCommentRequest
+		if err := c.ShouldBindJSON(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := bson.M{"tmdb_id": tmdbID, "comment_id": commentID, "deleted": bson.M{"$ne": true}}
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, filter).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		if comment.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this comment"})
+			return
+		}
+
+		screened, ok := screenContent(ctx, c, "", updateRequest.Content, minCommentLength)
+		if !ok {
+			return
+		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
+
+		set := bson.M{
+			"content":    text,
+			"edited":     true,
+			"updated_at": time.Now(),
+		}
+		if updateRequest.Spoiler != nil {
+			set["spoiler"] = *updateRequest.Spoiler
+		}
+		update := bson.M{"$set": set}
+		if len(spoilerRanges) > 0 {
+			set["spoiler_ranges"] = spoilerRanges
+		} else {
+			update["$unset"] = bson.M{"spoiler_ranges": ""}
+		}
+
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = mediaCommentCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&comment)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = holdForReview(ctx, screened, "media_comment", comment.CommentID, comment.UserID, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		comment.Hidden = comment.Hidden || screened.Verdict == services.VerdictHold
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Comment updated successfully",
+			"comment":         comment,
+			"held_for_review": screened.Verdict == services.VerdictHold,
+		})
+	}
+}
+
+func DeleteMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		commentID := c.Param("comment_id")
+		if commentID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "comment_id is required"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		role, _ := c.Get("role")
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": commentID, "deleted": bson.M{"$ne": true}}).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		if comment.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this comment"})
+			return
+		}
+
+		tombstoned, err := removeComment(ctx, comment)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = moderationService.CloseTarget(ctx, "media_comment", commentID, userID.(string)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Comment deleted successfully",
+			"tombstoned": tombstoned,
+		})
+	}
+}
+
+// removeComment hard-deletes a comment without replies. A comment that has
+// replies becomes a tombstone so the thread stays readable. Removing the last
+// reply of a tombstone removes the tombstone as well.
+func removeComment(ctx context.Context, comment models.Comment) (bool, error) {
+	result, err := mediaCommentCollection.DeleteOne(ctx, bson.M{"comment_id": comment.CommentID, "reply_count": bson.M{"$lte": 0}})
+	if err != nil {
+		return false, err
+	}
+
+	if result.DeletedCount == 0 {
+		tombstone := bson.M{
+			"$set": bson.M{
+				"deleted":    true,
+				"content":    "",
+				"user_name":  "",
+				"updated_at": time.Now(),
+			},
+			"$unset": bson.M{"spoiler_ranges": ""},
+		}
+		_, err = mediaCommentCollection.UpdateOne(ctx, bson.M{"comment_id": comment.CommentID}, tombstone)
+		if err == nil {
+			publishCommentDeleted(comment, true)
+		}
+		return true, err
+	}
+
+	publishCommentDeleted(comment, false)
+	if err = mediaCommentLikeService.DeleteTarget(ctx, comment.CommentID); err != nil {
+		return false, err
+	}
+
+	parentID := comment.ParentCommentID
+	for parentID != "" {
+		var parent models.Comment
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = mediaCommentCollection.FindOneAndUpdate(ctx, bson.M{"comment_id": parentID}, bson.M{"$inc": bson.M{"reply_count": -1}}, opts).Decode(&parent)
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if !parent.Deleted || parent.ReplyCount > 0 {
+			break
+		}
+
+		result, err = mediaCommentCollection.DeleteOne(ctx, bson.M{"comment_id": parentID, "deleted": true, "reply_count": bson.M{"$lte": 0}})
+		if err != nil {
+			return false, err
+		}
+		if result.DeletedCount == 0 {
+			break
+		}
+		publishCommentDeleted(parent, false)
+		if err = mediaCommentLikeService.DeleteTarget(ctx, parentID); err != nil {
+			return false, err
+		}
+		parentID = parent.ParentCommentID
+	}
+
+	return false, nil
+}
+
+// publishCommentDeleted tells live listeners a comment is gone or now a tombstone
+func publishCommentDeleted(comment models.Comment, tombstoned bool) {
+	publishMediaEvent(comment.TMDBID, "comment.deleted", comment.UserID, gin.H{
+		"comment_id":        comment.CommentID,
+		"parent_comment_id": comment.ParentCommentID,
+		"root_comment_id":   comment.RootCommentID,
+		"tombstoned":        tombstoned,
+	})
+}
+
+// maskHiddenComments blanks comments hidden by moderation, their author
+// included, so a placeholder doesn't tell who posted held or rejected
+// content. They stay in the listing, like tombstones, so their replies
+// remain readable.
+func maskHiddenComments(comments []models.Comment) {
+	for i := range comments {
+		if comments[i].Hidden {
+			comments[i].Content = ""
+			comments[i].UserID = ""
+			comments[i].UserName = ""
+			comments[i].SpoilerRanges = nil
+		}
+	}
+}
+
+// replyPreview holds the first replies and reply total of one thread
+type replyPreview struct {
+	RootCommentID string           `bson:"_id"`
+	Total         int64            `bson:"total"`
+	Replies       []models.Comment `bson:"replies"`
+}
+
+func GetMediaComments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": tmdbID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		repliesLimit := int64(defaultRepliesPreview)
+		if limitStr := c.Query("replies_limit"); limitStr != "" {
+			if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l >= 0 {
+				repliesLimit = min(l, maxPageLimit)
+			}
+		}
+
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, limit := getPagination(c)
+		filter := excludeAuthors(bson.M{"tmdb_id": tmdbID, "parent_comment_id": nil}, hidden)
+
+		// season and episode narrow the listing to one season or episode of a show
+		if seasonStr := c.Query("season"); seasonStr != "" {
+			season, err := strconv.Atoi(seasonStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season"})
+				return
+			}
+			filter["season"] = season
+		}
+		if episodeStr := c.Query("episode"); episodeStr != "" {
+			episode, err := strconv.Atoi(episodeStr)
+			if err != nil || filter["season"] == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "episode requires a valid season"})
+				return
+			}
+			filter["episode"] = episode
+		}
+		comments, total, err := findPage[models.Comment](ctx, mediaCommentCollection, filter, getSort(c, "like_count"), page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		previews, err := loadReplyPreviews(ctx, comments, repliesLimit, hidden)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		maskHiddenComments(comments)
+
+		threads := make([]models.CommentThread, len(comments))
+		for i, comment := range comments {
+			threads[i] = models.CommentThread{Comment: comment, Replies: []models.Comment{}}
+			if preview, ok := previews[comment.CommentID]; ok {
+				threads[i].Replies = preview.Replies
+				threads[i].TotalReplies = preview.Total
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"comments": threads,
+			"total":    total,
+			"page":     page,
+			"limit":    limit,
+		})
+	}
+}
+
+// loadReplyPreviews fetches the oldest replies of each thread in one
+// aggregation, skipping replies by the hidden authors
+func loadReplyPreviews(ctx context.Context, roots []models.Comment, repliesLimit int64, hidden []string) (map[string]replyPreview, error) {
+	previews := map[string]replyPreview{}
+	if len(roots) == 0 {
+		return previews, nil
+	}
+
+	ids := make([]string, len(roots))
+	for i, root := range roots {
+		ids[i] = root.CommentID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: excludeAuthors(bson.M{"root_comment_id": bson.M{"$in": ids}}, hidden)}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$root_comment_id",
+			"total":   bson.M{"$sum": 1},
+			"replies": bson.M{"$firstN": bson.M{"input": "$$ROOT", "n": max(repliesLimit, 1)}},
+		}}},
+	}
+
+	cursor, err := mediaCommentCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []replyPreview
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, preview := range results {
+		if int64(len(preview.Replies)) > repliesLimit {
+			preview.Replies = preview.Replies[:repliesLimit]
+		}
+		maskHiddenComments(preview.Replies)
+		previews[preview.RootCommentID] = preview
+	}
+
+	return previews, nil
+}
+
+func GetMediaCommentReplies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": c.Param("comment_id")}).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// A top-level comment lists its whole thread, a reply lists its direct replies
+		filter := bson.M{"root_comment_id": comment.CommentID}
+		if comment.Depth > 0 {
+			filter = bson.M{"parent_comment_id": comment.CommentID}
+		}
+		excludeAuthors(filter, hidden)
+
+		page, limit := getPagination(c)
+		replies, total, err := findPage[models.Comment](ctx, mediaCommentCollection, filter, getSort(c, "like_count"), page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		maskHiddenComments(replies)
+
+		thread := []models.Comment{comment}
+		maskHiddenComments(thread)
+
+		c.JSON(http.StatusOK, gin.H{
+			"comment": thread[0],
+			"replies": replies,
+			"total":   total,
+			"page":    page,
+			"limit":   limit,
+		})
+	}
+}
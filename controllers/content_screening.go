@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// recentContentLimit caps how many recent posts per collection the duplicate check looks at
+const recentContentLimit = 20
+
+// The shortest reviews and comments allowed once markup is stripped, matching
+// the min= of their request models
+const (
+	minReviewLength  = 10
+	minCommentLength = 1
+)
+
+var contentFilter = services.NewDefaultContentFilter(recentUserContent{})
+
+// recentUserContent feeds the duplicate check with a user's latest reviews and comments
+type recentUserContent struct{}
+
+func (recentUserContent) RecentContent(ctx context.Context, userID string, since time.Time) ([]string, error) {
+	filter := bson.M{"user_id": userID, "created_at": bson.M{"$gte": since}}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(recentContentLimit)
+
+	var texts []string
+
+	for _, collection := range []*mongo.Collection{mediaReviewCollection, movieReviewCollection} {
+		cursor, err := collection.Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+		var reviews []models.Review
+		if err = cursor.All(ctx, &reviews); err != nil {
+			return nil, err
+		}
+		for _, review := range reviews {
+			texts = append(texts, review.Comment)
+		}
+	}
+
+	cursor, err := mediaCommentCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var comments []models.Comment
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		texts = append(texts, comment.Content)
+	}
+
+	return texts, nil
+}
+
+// screenContent runs text through the content filter before it is stored. It
+// writes the error response and returns false when the text is rejected or is
+// shorter than minLength once markup is stripped. Edits pass an empty userID
+// so they aren't compared against the post they replace.
+func screenContent(ctx context.Context, c *gin.Context, userID, text string, minLength int) (*services.FilterResult, bool) {
+	result, err := contentFilter.Run(ctx, userID, text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if result.Verdict == services.VerdictReject {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Your content was rejected by the content filter",
+			"reasons": result.Reasons,
+		})
+		return nil, false
+	}
+
+	if utf8.RuneCountInString(strings.TrimSpace(result.Text)) < minLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Content must be at least %d characters once markup is removed", minLength)})
+		return nil, false
+	}
+
+	return result, true
+}
+
+// holdForReview sends content the filter flagged to the moderation queue. New
+// posts and review edits pass announce so their notifications and feed
+// entries go out once approved.
+func holdForReview(ctx context.Context, result *services.FilterResult, targetType, targetID, authorID string, announce bool) error {
+	if result.Verdict != services.VerdictHold {
+		return nil
+	}
+
+	reason := "automated: " + strings.Join(result.Reasons, ", ")
+	_, err := moderationService.Hold(ctx, targetType, targetID, authorID, result.Text, reason, announce)
+	return err
+}
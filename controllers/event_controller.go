@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// mediaEventHistory is how many events per title are kept for Last-Event-ID resume
+	mediaEventHistory = 256
+	// eventKeepAlive is how often an idle stream sends a comment so proxies keep it open
+	eventKeepAlive = 25 * time.Second
+)
+
+var mediaEventBroker services.EventBroker = services.NewMemoryBroker(mediaEventHistory)
+
+func mediaTopic(tmdbID int) string {
+	return "media:" + strconv.Itoa(tmdbID)
+}
+
+// publishMediaEvent sends a live update to everyone watching a title
+func publishMediaEvent(tmdbID int, eventType, actorID string, data any) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := mediaEventBroker.Publish(ctx, mediaTopic(tmdbID), eventType, actorID, data); err != nil {
+		log.Printf("publish %s for media %d: %v", eventType, tmdbID, err)
+	}
+}
+
+// GetMediaEvents streams comment.created, comment.deleted and rating.updated
+// events for a title as Server-Sent Events. A reconnecting client resumes from
+// the Last-Event-ID header (or last_event_id query parameter); when that point
+// is no longer buffered it gets a "reset" event and should reload.
+func GetMediaEvents() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": tmdbID})
+		if err != nil {
+			cancel()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			cancel()
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hiddenAuthors := map[string]bool{}
+		for _, author := range hidden {
+			hiddenAuthors[author] = true
+		}
+
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+
+		events, missed, err := mediaEventBroker.Subscribe(c.Request.Context(), mediaTopic(tmdbID), lastEventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Render(http.StatusOK, sse.Event{Event: "ready", Data: gin.H{"tmdb_id": tmdbID}})
+		if missed {
+			c.Render(-1, sse.Event{Event: "reset", Data: gin.H{"reason": "events since Last-Event-ID are no longer available"}})
+		}
+		c.Writer.Flush()
+
+		keepAlive := time.NewTicker(eventKeepAlive)
+		defer keepAlive.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return false
+				}
+				if hiddenAuthors[event.ActorID] {
+					return true
+				}
+				c.Render(-1, sse.Event{Id: event.ID, Event: event.Type, Data: event.Data})
+				return true
+			case <-keepAlive.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// activityExcerptLength is how many characters of a review a feed shows
+const activityExcerptLength = 200
+
+var followCollection *mongo.Collection = database.OpenCollection("follows")
+var activityCollection *mongo.Collection = database.OpenCollection("activities")
+var followService = services.NewFollowService(followCollection)
+var activityService = services.NewActivityService(activityCollection, userCollection)
+
+func mediaActivityKey(tmdbID int) string {
+	return "media:" + strconv.Itoa(tmdbID)
+}
+
+func movieActivityKey(imdbID string) string {
+	return "movie:" + imdbID
+}
+
+// recordActivity adds an entry to the actor's followers' feeds in the
+// background; like notifications, a failure is only logged
+func recordActivity(activity models.Activity) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := activityService.Record(ctx, activity); err != nil {
+			log.Printf("record %s activity for %s on %s: %v", activity.Type, activity.ActorID, activity.TargetKey, err)
+		}
+	}()
+}
+
+// reviewActivity describes a new review for the feed. Reviews held for
+// moderation stay out of feeds, and the excerpt stops before any spoiler.
+func reviewActivity(review models.Review, targetKey string) models.Activity {
+	excerpt := ""
+	if !review.Spoiler {
+		text := []rune(review.Comment)
+		end := len(text)
+		if len(review.SpoilerRanges) > 0 {
+			end = min(end, review.SpoilerRanges[0].Start)
+		}
+		excerpt = strings.TrimSpace(string(text[:min(end, activityExcerptLength)]))
+		if end > activityExcerptLength {
+			excerpt += "…"
+		}
+	}
+
+	return models.Activity{
+		ActorID:   review.UserID,
+		Type:      models.ActivityReview,
+		TargetKey: targetKey,
+		TMDBID:    review.TMDBID,
+		ImdbID:    review.ImdbID,
+		ReviewID:  review.ReviewID,
+		Excerpt:   excerpt,
+	}
+}
+
+// refreshReviewActivity brings a review's feed entry up to date after an edit.
+// A hidden review, such as an edit held for moderation, is taken out of feeds
+// until a moderator approves it.
+func refreshReviewActivity(review models.Review, targetKey string) {
+	if !review.Hidden {
+		recordActivity(reviewActivity(review, targetKey))
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := activityService.RemoveReview(ctx, review.ReviewID); err != nil {
+			log.Printf("remove review activity for %s: %v", review.ReviewID, err)
+		}
+	}()
+}
+
+func FollowUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		targetID := c.Param("user_id")
+		if targetID == userID.(string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+			return
+		}
+
+		count, err := userCollection.CountDocuments(ctx, bson.M{"user_id": targetID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		if !checkNotBlocked(ctx, c, userID.(string), targetID, "You cannot follow this user") {
+			return
+		}
+
+		if err = followService.Follow(ctx, userID.(string), targetID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "You are now following this user"})
+	}
+}
+
+func UnfollowUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		err := followService.Unfollow(ctx, userID.(string), c.Param("user_id"))
+		if err == services.ErrNotFollowing {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "You are no longer following this user"})
+	}
+}
+
+func GetFollowers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listFollows(c, "followee_id")
+	}
+}
+
+func GetFollowing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listFollows(c, "follower_id")
+	}
+}
+
+// listFollows pages through the follows where the route's user is in field
+func listFollows(c *gin.Context, field string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	page, limit := getPagination(c)
+	filter := bson.M{field: c.Param("user_id")}
+	follows, total, err := findPage[models.Follow](ctx, followCollection, filter, bson.D{{Key: "created_at", Value: -1}}, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"follows": follows,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetFeed returns the newest reviews, ratings and watchlist additions of the
+// users the caller follows. Pass next_cursor back as ?cursor= for the next page.
+func GetFeed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		followees, err := followService.FolloweeIDs(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Muted users stay followed but drop out of the feed
+		hidden, err := relationService.HiddenAuthors(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		actors := followees[:0]
+		for _, followee := range followees {
+			if !slices.Contains(hidden, followee) {
+				actors = append(actors, followee)
+			}
+		}
+
+		writeActivityPage(ctx, c, actors)
+	}
+}
+
+// GetUserActivity returns one user's activity, minus what they keep private
+func GetUserActivity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		targetID := c.Param("user_id")
+		if userID, exists := c.Get("userId"); exists {
+			if !checkNotBlocked(ctx, c, userID.(string), targetID, "You cannot view this user's activity") {
+				return
+			}
+		}
+
+		writeActivityPage(ctx, c, []string{targetID})
+	}
+}
+
+func writeActivityPage(ctx context.Context, c *gin.Context, actors []string) {
+	_, limit := getPagination(c)
+	activities, nextCursor, err := activityService.Feed(ctx, actors, c.Query("cursor"), limit)
+	if err == services.ErrInvalidCursor {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	activities, err = withoutHiddenReviews(ctx, activities)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities":  activities,
+		"next_cursor": nextCursor,
+	})
+}
+
+// withoutHiddenReviews drops review activities whose review a moderator has
+// since hidden
+func withoutHiddenReviews(ctx context.Context, activities []models.Activity) ([]models.Activity, error) {
+	var reviewIDs []string
+	for _, activity := range activities {
+		if activity.Type == models.ActivityReview {
+			reviewIDs = append(reviewIDs, activity.ReviewID)
+		}
+	}
+	if len(reviewIDs) == 0 {
+		return activities, nil
+	}
+
+	hidden := map[string]bool{}
+	filter := bson.M{"review_id": bson.M{"$in": reviewIDs}, "hidden": true}
+	for _, collection := range []*mongo.Collection{mediaReviewCollection, movieReviewCollection} {
+		var ids []string
+		if err := collection.Distinct(ctx, "review_id", filter).Decode(&ids); err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			hidden[id] = true
+		}
+	}
+
+	return slices.DeleteFunc(activities, func(activity models.Activity) bool {
+		return activity.Type == models.ActivityReview && hidden[activity.ReviewID]
+	}), nil
+}
+
+// GetUserProfile returns a user's public profile. Stats the user keeps
+// private are left out unless they are looking at their own profile.
+func GetUserProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		targetID := c.Param("user_id")
+
+		var user models.User
+		err := userCollection.FindOne(ctx, bson.M{"user_id": targetID}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		viewerID := ""
+		if userID, exists := c.Get("userId"); exists {
+			viewerID = userID.(string)
+			if !checkNotBlocked(ctx, c, viewerID, targetID, "You cannot view this profile") {
+				return
+			}
+		}
+		privacy := user.Privacy
+		if viewerID == targetID {
+			privacy = models.PrivacySettings{}
+		}
+
+		followers, following, err := followService.Counts(ctx, targetID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		stats := map[string]int64{"followers": followers, "following": following}
+
+		counts := []struct {
+			stat       string
+			collection *mongo.Collection
+			filter     bson.M
+			private    bool
+		}{
+			{"media_reviews", mediaReviewCollection, bson.M{"user_id": targetID, "hidden": bson.M{"$ne": true}}, privacy.HideReviews},
+			{"movie_reviews", movieReviewCollection, bson.M{"user_id": targetID, "hidden": bson.M{"$ne": true}}, privacy.HideReviews},
+			{"media_ratings", mediaRatingCollection, bson.M{"user_id": targetID}, privacy.HideRatings},
+			{"movie_ratings", movieRatingCollection, bson.M{"user_id": targetID}, privacy.HideRatings},
+			{"watchlist", watchlistCollection, bson.M{"user_id": targetID}, privacy.HideWatchlist},
+		}
+		for _, count := range counts {
+			if count.private {
+				continue
+			}
+			n, err := count.collection.CountDocuments(ctx, count.filter)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			stats[count.stat] = n
+		}
+
+		isFollowing := false
+		if viewerID != "" && viewerID != targetID {
+			isFollowing, err = followService.IsFollowing(ctx, viewerID, targetID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, models.UserProfile{
+			UserID:    user.UserID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			CreatedAt: user.CreatedAt,
+			Stats:     stats,
+			Following: isFollowing,
+		})
+	}
+}
+
+func GetPrivacySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var user models.User
+		err := userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not find user"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"privacy": user.Privacy})
+	}
+}
+
+// UpdatePrivacySettings changes the settings present in the request and
+// leaves the rest as they are
+func UpdatePrivacySettings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var privacyRequest models.UpdatePrivacyRequest
+		if err := c.ShouldBindJSON(&privacyRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		set := bson.M{"updated_at": time.Now()}
+		if privacyRequest.HideReviews != nil {
+			set["privacy.hide_reviews"] = *privacyRequest.HideReviews
+		}
+		if privacyRequest.HideRatings != nil {
+			set["privacy.hide_ratings"] = *privacyRequest.HideRatings
+		}
+		if privacyRequest.HideWatchlist != nil {
+			set["privacy.hide_watchlist"] = *privacyRequest.HideWatchlist
+		}
+
+		var user models.User
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := userCollection.FindOneAndUpdate(ctx, bson.M{"user_id": userID}, bson.M{"$set": set}, opts).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update privacy settings"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Privacy settings updated successfully",
+			"privacy": user.Privacy,
+		})
+	}
+}
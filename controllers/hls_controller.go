@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/Har2yQn78/Stream_Platform/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var videoAssetCollection *mongo.Collection = database.OpenCollection("video_assets")
+
+// videoAssetFilter matches the asset for the movie or episode in the route.
+// Movies have no season or episode, so the filter asks for them to be missing.
+func videoAssetFilter(c *gin.Context) (bson.M, bool) {
+	tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+		return nil, false
+	}
+
+	filter := bson.M{"tmdb_id": tmdbID, "season": nil, "episode": nil}
+	if seasonStr := c.Param("season"); seasonStr != "" {
+		season, err := strconv.Atoi(seasonStr)
+		if err != nil || season < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season"})
+			return nil, false
+		}
+		episode, err := strconv.Atoi(c.Param("episode"))
+		if err != nil || episode < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid episode"})
+			return nil, false
+		}
+		filter["season"] = season
+		filter["episode"] = episode
+	}
+	return filter, true
+}
+
+// ServeHLS serves the generated master playlist at master.m3u8, the
+// variant playlists and segments of each rendition at <rendition>/<file>
+// and the title's subtitle tracks.
+// Requests are authorised by a signed playback URL, see middleware.PlaybackAuth.
+func ServeHLS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if _, exists := c.Get("userId"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		filter, ok := videoAssetFilter(c)
+		if !ok {
+			return
+		}
+
+		var asset models.VideoAsset
+		err := videoAssetCollection.FindOne(ctx, filter).Decode(&asset)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This media has no stream"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Playlists pass the signed playback query on to the URIs they list
+		query := utils.PlaybackQuery(c.Request.URL.Query()).Encode()
+
+		requested := strings.TrimPrefix(c.Param("path"), "/")
+		if requested == "master.m3u8" {
+			subtitles, err := findAll[models.SubtitleTrack](ctx, subtitleTrackCollection, filter, trackSort)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			// The master playlist changes whenever renditions are re-ingested
+			c.Header("Cache-Control", "private, no-cache")
+			c.Data(http.StatusOK, mediaContentTypes[".m3u8"], []byte(services.MasterPlaylist(asset.Renditions, subtitles, query)))
+			return
+		}
+		// Subtitle URIs have no slash, so they can't clash with a rendition
+		if trackFile, ok := strings.CutPrefix(requested, "subtitles-"); ok && !strings.Contains(trackFile, "/") {
+			serveHLSSubtitles(ctx, c, filter, trackFile, query)
+			return
+		}
+
+		name, file, _ := strings.Cut(requested, "/")
+		var rendition *models.Rendition
+		for i := range asset.Renditions {
+			if asset.Renditions[i].Name == name {
+				rendition = &asset.Renditions[i]
+				break
+			}
+		}
+		if rendition == nil || !filepath.IsLocal(file) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+
+		key := path.Join(asset.BaseDir, path.Dir(rendition.Playlist), file)
+		if path.Ext(key) != ".m3u8" {
+			serveStoredObject(c, key)
+			return
+		}
+
+		playlist, err := readStoredText(ctx, key)
+		if errors.Is(err, services.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Cache-Control", "private, no-cache")
+		c.Data(http.StatusOK, mediaContentTypes[".m3u8"], services.AppendPlaylistQuery(playlist, query))
+	}
+}
+
+// serveHLSSubtitles serves the media playlist of a subtitle track at
+// subtitles-<track_id>.m3u8 and its WebVTT file at subtitles-<track_id>.vtt
+func serveHLSSubtitles(ctx context.Context, c *gin.Context, scope bson.M, trackFile, query string) {
+	ext := path.Ext(trackFile)
+	filter := bson.M{"track_id": strings.TrimSuffix(trackFile, ext)}
+	for key, value := range scope {
+		filter[key] = value
+	}
+
+	var track models.SubtitleTrack
+	err := subtitleTrackCollection.FindOne(ctx, filter).Decode(&track)
+	if err == mongo.ErrNoDocuments || (ext != ".m3u8" && ext != ".vtt") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if ext == ".vtt" {
+		serveStoredObject(c, track.StorageKey)
+		return
+	}
+	duration := time.Duration(track.Duration * float64(time.Second))
+	playlist := services.SubtitlePlaylist(services.SubtitleFileURI(track.TrackID), duration)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, mediaContentTypes[".m3u8"], services.AppendPlaylistQuery([]byte(playlist), query))
+}
+
+// maxTextSize caps how much of a stored playlist or WebVTT file is read
+// for rewriting
+const maxTextSize = 4 << 20
+
+// readStoredText reads a playlist or WebVTT file from storage
+func readStoredText(ctx context.Context, key string) ([]byte, error) {
+	body, info, err := mediaStorage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if info.Size > maxTextSize {
+		return nil, fmt.Errorf("%s is too large", key)
+	}
+	return io.ReadAll(io.LimitReader(body, maxTextSize))
+}
+
+// GetVideoAssets lists the renditions registered for a title and its episodes
+func GetVideoAssets() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		assets, err := findAll[models.VideoAsset](ctx, videoAssetCollection, bson.M{"tmdb_id": tmdbID}, bson.D{{Key: "season", Value: 1}, {Key: "episode", Value: 1}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range assets {
+			assets[i].MasterURL = services.HLSMasterPath(assets[i].TMDBID, assets[i].Season, assets[i].Episode)
+		}
+
+		c.JSON(http.StatusOK, assets)
+	}
+}
@@ -0,0 +1,635 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	maxListItems = 500
+	// listPreviewItems is how many items each list shows in browse results
+	listPreviewItems = 4
+)
+
+var listCollection *mongo.Collection = database.OpenCollection("lists")
+var listLikeService = services.NewVoteService(voteCollection, listCollection, "media_list", "list_id", "like_count", "", "")
+
+// mediaByTMDBID loads the media documents for a set of tmdb ids. Video URLs
+// are left out; players get them from POST /media/:tmdb_id/playback.
+func mediaByTMDBID(ctx context.Context, tmdbIDs []int) (map[int]*models.Media, error) {
+	media, err := findAll[models.Media](ctx, mediaCollection, bson.M{"tmdb_id": bson.M{"$in": tmdbIDs}}, bson.D{{Key: "tmdb_id", Value: 1}})
+	if err != nil {
+		return nil, err
+	}
+	byTMDBID := make(map[int]*models.Media, len(media))
+	for i := range media {
+		media[i].VideoURL = ""
+		byTMDBID[media[i].TMDBID] = &media[i]
+	}
+	return byTMDBID, nil
+}
+
+// findViewableList loads the list in the route. Private lists are only
+// visible to their owner and look missing to everyone else.
+func findViewableList(ctx context.Context, c *gin.Context) (*models.MediaList, bool) {
+	var list models.MediaList
+	err := listCollection.FindOne(ctx, bson.M{"list_id": c.Param("list_id")}).Decode(&list)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return nil, false
+	}
+
+	userID, exists := c.Get("userId")
+	if exists && userID.(string) == list.UserID {
+		return &list, true
+	}
+	if list.Visibility == models.ListPrivate {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return nil, false
+	}
+	if exists && !checkNotBlocked(ctx, c, userID.(string), list.UserID, "You cannot view this list") {
+		return nil, false
+	}
+	return &list, true
+}
+
+// findOwnedList loads the list in the route for a change by its owner
+func findOwnedList(ctx context.Context, c *gin.Context, userID string) (*models.MediaList, bool) {
+	var list models.MediaList
+	err := listCollection.FindOne(ctx, bson.M{"list_id": c.Param("list_id")}).Decode(&list)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return nil, false
+	}
+	if list.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to change this list"})
+		return nil, false
+	}
+	return &list, true
+}
+
+func CreateList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var listRequest models.CreateListRequest
+		if err := c.ShouldBindJSON(&listRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&listRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var user models.User
+		err := userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not find user"})
+			return
+		}
+
+		visibility := listRequest.Visibility
+		if visibility == "" {
+			visibility = models.ListPublic
+		}
+
+		now := time.Now()
+		list := models.MediaList{
+			ListID:      bson.NewObjectID().Hex(),
+			UserID:      userID.(string),
+			UserName:    user.FirstName + " " + user.LastName,
+			Name:        listRequest.Name,
+			Description: listRequest.Description,
+			Visibility:  visibility,
+			Items:       []models.ListItem{},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		if _, err = listCollection.InsertOne(ctx, list); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "List created successfully",
+			"list":    list,
+		})
+	}
+}
+
+// GetLists browses public lists, most liked first or with ?sort=newest
+func GetLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		filter := excludeAuthors(bson.M{"visibility": models.ListPublic, "item_count": bson.M{"$gt": 0}}, hidden)
+
+		sort := bson.D{{Key: "like_count", Value: -1}, {Key: "updated_at", Value: -1}}
+		if c.Query("sort") == "newest" {
+			sort = bson.D{{Key: "created_at", Value: -1}}
+		}
+
+		writeListPage(ctx, c, filter, sort)
+	}
+}
+
+// GetUserLists returns a user's public lists, or all of them to the owner
+func GetUserLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		targetID := c.Param("user_id")
+		filter := bson.M{"user_id": targetID}
+
+		userID, exists := c.Get("userId")
+		if !exists || userID.(string) != targetID {
+			if exists && !checkNotBlocked(ctx, c, userID.(string), targetID, "You cannot view this user's lists") {
+				return
+			}
+			filter["visibility"] = models.ListPublic
+		}
+
+		writeListPage(ctx, c, filter, bson.D{{Key: "updated_at", Value: -1}})
+	}
+}
+
+// writeListPage responds with one page of lists, each trimmed to a preview
+// of its first items
+func writeListPage(ctx context.Context, c *gin.Context, filter bson.M, sort bson.D) {
+	page, limit := getPagination(c)
+	lists, total, err := findPage[models.MediaList](ctx, listCollection, filter, sort, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var tmdbIDs []int
+	for i := range lists {
+		lists[i].Items = lists[i].Items[:min(len(lists[i].Items), listPreviewItems)]
+		for _, item := range lists[i].Items {
+			tmdbIDs = append(tmdbIDs, item.TMDBID)
+		}
+	}
+	media, err := mediaByTMDBID(ctx, tmdbIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range lists {
+		for j := range lists[i].Items {
+			lists[i].Items[j].Media = media[lists[i].Items[j].TMDBID]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lists": lists,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func GetList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		list, ok := findViewableList(ctx, c)
+		if !ok {
+			return
+		}
+
+		tmdbIDs := make([]int, 0, len(list.Items))
+		for _, item := range list.Items {
+			tmdbIDs = append(tmdbIDs, item.TMDBID)
+		}
+		media, err := mediaByTMDBID(ctx, tmdbIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range list.Items {
+			list.Items[i].Media = media[list.Items[i].TMDBID]
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func UpdateList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var updateRequest models.UpdateListRequest
+		if err := c.ShouldBindJSON(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, ok := findOwnedList(ctx, c, userID.(string)); !ok {
+			return
+		}
+
+		set := bson.M{"updated_at": time.Now()}
+		if updateRequest.Name != nil {
+			set["name"] = *updateRequest.Name
+		}
+		if updateRequest.Description != nil {
+			set["description"] = *updateRequest.Description
+		}
+		if updateRequest.Visibility != nil {
+			set["visibility"] = *updateRequest.Visibility
+		}
+
+		var list models.MediaList
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := listCollection.FindOneAndUpdate(ctx, bson.M{"list_id": c.Param("list_id")}, bson.M{"$set": set}, opts).Decode(&list)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "List updated successfully",
+			"list":    list,
+		})
+	}
+}
+
+func DeleteList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		role, _ := c.Get("role")
+
+		var list models.MediaList
+		err := listCollection.FindOne(ctx, bson.M{"list_id": c.Param("list_id")}).Decode(&list)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+
+		if list.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this list"})
+			return
+		}
+
+		if _, err = listCollection.DeleteOne(ctx, bson.M{"list_id": list.ListID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = listLikeService.DeleteTarget(ctx, list.ListID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
+	}
+}
+
+func AddListItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var itemRequest models.AddListItemRequest
+		if err := c.ShouldBindJSON(&itemRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&itemRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		list, ok := findOwnedList(ctx, c, userID.(string))
+		if !ok {
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": itemRequest.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		item := models.ListItem{
+			TMDBID:  itemRequest.TMDBID,
+			Note:    itemRequest.Note,
+			AddedAt: time.Now(),
+		}
+		push := bson.M{"$each": bson.A{item}}
+		if itemRequest.Position != nil {
+			push["$position"] = *itemRequest.Position
+		}
+
+		// The filter guards against duplicates and the size cap atomically
+		filter := bson.M{
+			"list_id":       list.ListID,
+			"items.tmdb_id": bson.M{"$ne": itemRequest.TMDBID},
+			"item_count":    bson.M{"$lt": maxListItems},
+		}
+		update := bson.M{
+			"$push": bson.M{"items": push},
+			"$inc":  bson.M{"item_count": 1},
+			"$set":  bson.M{"updated_at": item.AddedAt},
+		}
+
+		var updated models.MediaList
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = listCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			if slices.ContainsFunc(list.Items, func(existing models.ListItem) bool { return existing.TMDBID == itemRequest.TMDBID }) {
+				c.JSON(http.StatusConflict, gin.H{"error": "This title is already on the list"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A list can hold at most " + strconv.Itoa(maxListItems) + " titles"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Title added to the list",
+			"list":    updated,
+		})
+	}
+}
+
+func UpdateListItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var updateRequest models.UpdateListItemRequest
+		if err := c.ShouldBindJSON(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&updateRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		list, ok := findOwnedList(ctx, c, userID.(string))
+		if !ok {
+			return
+		}
+
+		filter := bson.M{"list_id": list.ListID, "items.tmdb_id": tmdbID}
+		update := bson.M{"$set": bson.M{"items.$.note": updateRequest.Note, "updated_at": time.Now()}}
+
+		var updated models.MediaList
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = listCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Title is not on the list"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "List item updated successfully",
+			"list":    updated,
+		})
+	}
+}
+
+func RemoveListItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		list, ok := findOwnedList(ctx, c, userID.(string))
+		if !ok {
+			return
+		}
+
+		filter := bson.M{"list_id": list.ListID, "items.tmdb_id": tmdbID}
+		update := bson.M{
+			"$pull": bson.M{"items": bson.M{"tmdb_id": tmdbID}},
+			"$inc":  bson.M{"item_count": -1},
+			"$set":  bson.M{"updated_at": time.Now()},
+		}
+
+		var updated models.MediaList
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = listCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Title is not on the list"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Title removed from the list",
+			"list":    updated,
+		})
+	}
+}
+
+// ReorderList puts the list's items in the order of the request, which must
+// name every item exactly once
+func ReorderList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var reorderRequest models.ReorderListRequest
+		if err := c.ShouldBindJSON(&reorderRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		list, ok := findOwnedList(ctx, c, userID.(string))
+		if !ok {
+			return
+		}
+
+		byTMDBID := make(map[int]models.ListItem, len(list.Items))
+		for _, item := range list.Items {
+			byTMDBID[item.TMDBID] = item
+		}
+
+		items := make([]models.ListItem, 0, len(reorderRequest.TMDBIDs))
+		for _, tmdbID := range reorderRequest.TMDBIDs {
+			item, ok := byTMDBID[tmdbID]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "tmdb_ids must list every item on the list exactly once"})
+				return
+			}
+			delete(byTMDBID, tmdbID)
+			items = append(items, item)
+		}
+		if len(byTMDBID) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tmdb_ids must list every item on the list exactly once"})
+			return
+		}
+
+		// Matching on updated_at rejects the new order if the list changed
+		// since it was read
+		filter := bson.M{"list_id": list.ListID, "updated_at": list.UpdatedAt}
+		update := bson.M{"$set": bson.M{"items": items, "updated_at": time.Now()}}
+
+		var updated models.MediaList
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := listCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "The list changed while it was being reordered, please try again"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "List reordered successfully",
+			"list":    updated,
+		})
+	}
+}
+
+func LikeList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		list, ok := findViewableList(ctx, c)
+		if !ok {
+			return
+		}
+
+		if list.UserID == userID.(string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot like your own list"})
+			return
+		}
+
+		counts, _, err := listLikeService.Cast(ctx, list.ListID, userID.(string), 1)
+		respondVote(c, counts, err)
+	}
+}
+
+func UnlikeList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		counts, err := listLikeService.Clear(ctx, c.Param("list_id"), userID.(string))
+		respondVote(c, counts, err)
+	}
+}
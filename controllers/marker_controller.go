@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var episodeCollection *mongo.Collection = database.OpenCollection("episodes")
+
+// episodeParams reads the show, season and episode from the route
+func episodeParams(c *gin.Context) (tmdbID, season, episode int, ok bool) {
+	tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+		return 0, 0, 0, false
+	}
+	season, err = strconv.Atoi(c.Param("season"))
+	if err != nil || season < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season"})
+		return 0, 0, 0, false
+	}
+	episode, err = strconv.Atoi(c.Param("episode"))
+	if err != nil || episode < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid episode"})
+		return 0, 0, 0, false
+	}
+	return tmdbID, season, episode, true
+}
+
+// findEpisode returns the record of an episode, or nil when nothing has
+// been set for it yet
+func findEpisode(ctx context.Context, tmdbID, season, episode int) (*models.Episode, error) {
+	var record models.Episode
+	err := episodeCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "season": season, "episode": episode}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// bindMarkers reads and validates a SetMarkersRequest
+func bindMarkers(c *gin.Context) (*models.SetMarkersRequest, bool) {
+	var request models.SetMarkersRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return nil, false
+	}
+
+	if err := mediaValidator.Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &request, true
+}
+
+// SetMediaMarkers replaces a movie's intro, recap and credits markers and
+// its chapters. They are checked against the movie's runtime.
+func SetMediaMarkers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		request, ok := bindMarkers(c)
+		if !ok {
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if media.MediaType != models.MediaTypeMovie {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Shows have markers on each episode"})
+			return
+		}
+
+		runtime := time.Duration(media.Runtime) * time.Minute
+		if err := services.CheckMarkers(request.Markers, request.Chapters, runtime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		update := bson.M{"$set": bson.M{"markers": request.Markers, "chapters": request.Chapters, "updated_at": time.Now()}}
+		if _, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+// SetEpisodeMarkers replaces the markers and chapters of an episode. The
+// episode's runtime is fetched from TMDB the first time, to check them against.
+func SetEpisodeMarkers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, season, episode, ok := episodeParams(c)
+		if !ok {
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		request, ok := bindMarkers(c)
+		if !ok {
+			return
+		}
+
+		var media models.Media
+		err := mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if media.MediaType != models.MediaTypeTV {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only shows have episodes"})
+			return
+		}
+
+		record, err := findEpisode(ctx, tmdbID, season, episode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		runtime := 0
+		if record != nil {
+			runtime = record.Runtime
+		}
+		if runtime == 0 {
+			details, err := mediaTmdbService.GetEpisodeDetails(tmdbID, season, episode)
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Could not fetch episode details from TMDB: " + err.Error()})
+				return
+			}
+			runtime = details.Runtime
+		}
+
+		if err := services.CheckMarkers(request.Markers, request.Chapters, time.Duration(runtime)*time.Minute); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := bson.M{"tmdb_id": tmdbID, "season": season, "episode": episode}
+		update := bson.M{"$set": bson.M{
+			"runtime":    runtime,
+			"markers":    request.Markers,
+			"chapters":   request.Chapters,
+			"updated_by": userID.(string),
+			"updated_at": time.Now(),
+		}}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+		var updated models.Episode
+		if err := episodeCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// GetEpisode returns an episode's runtime, markers and chapters
+func GetEpisode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, season, episode, ok := episodeParams(c)
+		if !ok {
+			return
+		}
+
+		record, err := findEpisode(ctx, tmdbID, season, episode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if record == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Episode not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, record)
+	}
+}
@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Har2yQn78/Stream_Platform/database"
@@ -17,6 +20,10 @@ import (
 )
 
 var mediaCollection *mongo.Collection = database.OpenCollection("media")
+var mediaReviewCollection *mongo.Collection = database.OpenCollection("media_reviews")
+var mediaCommentCollection *mongo.Collection = database.OpenCollection("media_comments")
+var mediaRatingCollection *mongo.Collection = database.OpenCollection("media_ratings")
+var mediaRatingService = services.NewRatingService(mediaRatingCollection, mediaCollection, "tmdb_id")
 var mediaValidator = validator.New()
 var mediaTmdbService = services.NewTMDBService()
 
@@ -43,6 +50,7 @@ func GetAllMedia() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		hideVideoURLs(c, media)
 
 		c.JSON(http.StatusOK, media)
 	}
@@ -67,10 +75,123 @@ func GetMediaByTMDBID() gin.HandlerFunc {
 			return
 		}
 
+		if err = embedMediaRelations(ctx, c, &media, c.Query("embed")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hideVideoURLs(c, []models.Media{media})
+
 		c.JSON(http.StatusOK, media)
 	}
 }
 
+// embedMediaRelations fills in the reviews, comments and ratings named in embed
+// (a comma separated list, or "all"), giving the response its pre-split shape.
+// Reviews and comments by authors the caller blocked or muted are left out.
+func embedMediaRelations(ctx context.Context, c *gin.Context, media *models.Media, embed string) error {
+	if embed == "" {
+		return nil
+	}
+	hidden, err := hiddenAuthorsFor(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range strings.Split(embed, ",") {
+		wanted[strings.TrimSpace(name)] = true
+	}
+	all := wanted["all"]
+
+	filter := bson.M{"tmdb_id": media.TMDBID}
+	oldestFirst := bson.D{{Key: "created_at", Value: 1}}
+
+	if all || wanted["reviews"] {
+		visible := excludeAuthors(bson.M{"tmdb_id": media.TMDBID, "hidden": bson.M{"$ne": true}}, hidden)
+		if media.Reviews, err = findAll[models.Review](ctx, mediaReviewCollection, visible, oldestFirst); err != nil {
+			return err
+		}
+	}
+	if all || wanted["comments"] {
+		authored := excludeAuthors(bson.M{"tmdb_id": media.TMDBID}, hidden)
+		if media.Comments, err = findAll[models.Comment](ctx, mediaCommentCollection, authored, oldestFirst); err != nil {
+			return err
+		}
+		maskHiddenComments(media.Comments)
+	}
+	if all || wanted["ratings"] {
+		if media.Ratings, err = findAll[models.Rating](ctx, mediaRatingCollection, filter, oldestFirst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mediaFromTMDB builds a media document from TMDB's details for a title
+func mediaFromTMDB(tmdbID int, mediaType models.MediaType, videoURL string) (models.Media, error) {
+	var media models.Media
+
+	if mediaType == models.MediaTypeMovie {
+		details, err := mediaTmdbService.GetMovieDetails(tmdbID)
+		if err != nil {
+			return media, errors.New("Could not fetch movie details from TMDB: " + err.Error())
+		}
+
+		genres := make([]models.Genre, len(details.Genres))
+		for i, g := range details.Genres {
+			genres[i] = models.Genre{
+				GenreID:   g.ID,
+				GenreName: g.Name,
+			}
+		}
+
+		media = models.Media{
+			TMDBID:       details.ID,
+			ImdbID:       details.ImdbID,
+			MediaType:    models.MediaTypeMovie,
+			Title:        details.Title,
+			Overview:     details.Overview,
+			PosterPath:   mediaTmdbService.GetFullPosterURL(details.PosterPath, "w500"),
+			BackdropPath: mediaTmdbService.GetFullBackdropURL(details.BackdropPath, "w1280"),
+			VideoURL:     videoURL,
+			ReleaseDate:  details.ReleaseDate,
+			Genres:       genres,
+			Runtime:      details.Runtime,
+		}
+	} else if mediaType == models.MediaTypeTV {
+		details, err := mediaTmdbService.GetTVDetails(tmdbID)
+		if err != nil {
+			return media, errors.New("Could not fetch TV details from TMDB: " + err.Error())
+		}
+
+		genres := make([]models.Genre, len(details.Genres))
+		for i, g := range details.Genres {
+			genres[i] = models.Genre{
+				GenreID:   g.ID,
+				GenreName: g.Name,
+			}
+		}
+
+		media = models.Media{
+			TMDBID:           details.ID,
+			MediaType:        models.MediaTypeTV,
+			Title:            details.Name,
+			Overview:         details.Overview,
+			PosterPath:       mediaTmdbService.GetFullPosterURL(details.PosterPath, "w500"),
+			BackdropPath:     mediaTmdbService.GetFullBackdropURL(details.BackdropPath, "w1280"),
+			VideoURL:         videoURL,
+			ReleaseDate:      details.FirstAirDate,
+			Genres:           genres,
+			NumberOfSeasons:  details.NumberOfSeasons,
+			NumberOfEpisodes: details.NumberOfEpisodes,
+			InProduction:     details.InProduction,
+		}
+	}
+
+	return media, nil
+}
+
 func AddMedia() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -103,72 +224,24 @@ func AddMedia() gin.HandlerFunc {
 			return
 		}
 
-		var media models.Media
-
-		if request.MediaType == models.MediaTypeMovie {
-			details, err := mediaTmdbService.GetMovieDetails(request.TMDBID)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Could not fetch movie details from TMDB: " + err.Error()})
+		if request.VideoFile != "" {
+			if _, err := mediaStorage.Stat(ctx, request.VideoFile); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "video_file is not a file in media storage"})
 				return
 			}
+		}
 
-			genres := make([]models.Genre, len(details.Genres))
-			for i, g := range details.Genres {
-				genres[i] = models.Genre{
-					GenreID:   g.ID,
-					GenreName: g.Name,
-				}
-			}
-
-			media = models.Media{
-				TMDBID:       details.ID,
-				ImdbID:       details.ImdbID,
-				MediaType:    models.MediaTypeMovie,
-				Title:        details.Title,
-				Overview:     details.Overview,
-				PosterPath:   mediaTmdbService.GetFullPosterURL(details.PosterPath, "w500"),
-				BackdropPath: mediaTmdbService.GetFullBackdropURL(details.BackdropPath, "w1280"),
-				VideoURL:     request.VideoURL,
-				ReleaseDate:  details.ReleaseDate,
-				Genres:       genres,
-				Runtime:      details.Runtime,
-			}
-		} else if request.MediaType == models.MediaTypeTV {
-			details, err := mediaTmdbService.GetTVDetails(request.TMDBID)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Could not fetch TV details from TMDB: " + err.Error()})
-				return
-			}
-
-			genres := make([]models.Genre, len(details.Genres))
-			for i, g := range details.Genres {
-				genres[i] = models.Genre{
-					GenreID:   g.ID,
-					GenreName: g.Name,
-				}
-			}
-
-			media = models.Media{
-				TMDBID:           details.ID,
-				MediaType:        models.MediaTypeTV,
-				Title:            details.Name,
-				Overview:         details.Overview,
-				PosterPath:       mediaTmdbService.GetFullPosterURL(details.PosterPath, "w500"),
-				BackdropPath:     mediaTmdbService.GetFullBackdropURL(details.BackdropPath, "w1280"),
-				VideoURL:         request.VideoURL,
-				ReleaseDate:      details.FirstAirDate,
-				Genres:           genres,
-				NumberOfSeasons:  details.NumberOfSeasons,
-				NumberOfEpisodes: details.NumberOfEpisodes,
-				InProduction:     details.InProduction,
-			}
+		media, err := mediaFromTMDB(request.TMDBID, request.MediaType, request.VideoURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		media.VideoFile = request.VideoFile
+		media.Sources = services.DefaultSources(media.VideoURL, media.VideoFile)
 
-		media.Reviews = []models.Review{}
-		media.Comments = []models.Comment{}
-		media.Ratings = []models.Rating{}
 		media.AverageRating = 0.0
 		media.TotalRatings = 0
+		media.RatingSum = 0.0
 		media.AddedBy = userID.(string)
 		media.CreatedAt = time.Now()
 		media.UpdatedAt = time.Now()
@@ -179,6 +252,11 @@ func AddMedia() gin.HandlerFunc {
 			return
 		}
 
+		// The media is in; a failure to close its request only needs a log line
+		if err = closeTitleRequest(ctx, media, userID.(string)); err != nil {
+			log.Printf("closing title request for media %d: %v", media.TMDBID, err)
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
 			"message":   "Media added successfully",
 			"insert_id": result.InsertedID,
@@ -224,49 +302,64 @@ func AddMediaReview() gin.HandlerFunc {
 			return
 		}
 
-		var media models.Media
-		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": tmdbID})
 		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
 			return
 		}
 
-		for _, r := range media.Reviews {
-			if r.UserID == userID.(string) {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "You have already reviewed this media. Use update endpoint to modify your review."})
-				return
-			}
+		screened, ok := screenContent(ctx, c, userID.(string), reviewRequest.Comment, minReviewLength)
+		if !ok {
+			return
 		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
 
 		review := models.Review{
-			ReviewID:  bson.NewObjectID().Hex(),
-			UserID:    userID.(string),
-			UserName:  user.FirstName + " " + user.LastName,
-			Comment:   reviewRequest.Comment,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ReviewID:      bson.NewObjectID().Hex(),
+			TMDBID:        tmdbID,
+			UserID:        userID.(string),
+			UserName:      user.FirstName + " " + user.LastName,
+			Comment:       text,
+			Hidden:        screened.Verdict == services.VerdictHold,
+			Spoiler:       reviewRequest.Spoiler,
+			SpoilerRanges: spoilerRanges,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
 		}
 
-		update := bson.M{
-			"$push": bson.M{"reviews": review},
-			"$set":  bson.M{"updated_at": time.Now()},
+		result, err := mediaReviewCollection.InsertOne(ctx, review)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You have already reviewed this media. Use update endpoint to modify your review."})
+			return
 		}
-
-		result, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if err = holdForReview(ctx, screened, "media_review", review.ReviewID, userID.(string), true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !review.Hidden {
+			recordActivity(reviewActivity(review, mediaActivityKey(tmdbID)))
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
-			"message": "Review added successfully",
-			"review":  review,
-			"result":  result,
+			"message":         "Review added successfully",
+			"review":          review,
+			"result":          result,
+			"held_for_review": review.Hidden,
 		})
 	}
 }
 
-func AddMediaComment() gin.HandlerFunc {
+func UpdateMediaReview() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
@@ -278,67 +371,78 @@ func AddMediaComment() gin.HandlerFunc {
 			return
 		}
 
+		reviewID := c.Param("review_id")
+		if reviewID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "review_id is required"})
+			return
+		}
+
 		userID, exists := c.Get("userId")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 			return
 		}
 
-		var commentRequest models.AddCommentRequest
-		if err := c.ShouldBindJSON(&commentRequest); err != nil {
+		role, _ := c.Get("role")
+
+		var updateRequest models.UpdateReviewRequest
+		if err := c.ShouldBindJSON(&updateRequest); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 			return
 		}
 
-		if err := mediaValidator.Struct(&commentRequest); err != nil {
+		if err := mediaValidator.Struct(&updateRequest); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var user models.User
-		userCollection := database.OpenCollection("users")
-		err = userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user)
+		filter := bson.M{"tmdb_id": tmdbID, "review_id": reviewID}
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, filter).Decode(&review)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not find user"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 			return
 		}
 
-		var media models.Media
-		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		if review.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to update this review"})
 			return
 		}
 
-		comment := models.Comment{
-			CommentID: bson.NewObjectID().Hex(),
-			UserID:    userID.(string),
-			UserName:  user.FirstName + " " + user.LastName,
-			Content:   commentRequest.Content,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+		screened, ok := screenContent(ctx, c, "", updateRequest.Comment, minReviewLength)
+		if !ok {
+			return
 		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
 
-		update := bson.M{
-			"$push": bson.M{"comments": comment},
-			"$set":  bson.M{"updated_at": time.Now()},
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = mediaReviewCollection.FindOneAndUpdate(ctx, filter, reviewEditPipeline(text, spoilerRanges, updateRequest.Spoiler, userID.(string), time.Now()), opts).Decode(&review)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
 		}
-
-		result, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{
-			"message": "Comment added successfully",
-			"comment": comment,
-			"result":  result,
+		if err = holdForReview(ctx, screened, "media_review", review.ReviewID, review.UserID, true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		review.Hidden = review.Hidden || screened.Verdict == services.VerdictHold
+		refreshReviewActivity(review, mediaActivityKey(tmdbID))
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Review updated successfully",
+			"review":          review,
+			"held_for_review": screened.Verdict == services.VerdictHold,
 		})
 	}
 }
 
-func DeleteMediaComment() gin.HandlerFunc {
+func DeleteMediaReview() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
@@ -350,9 +454,9 @@ func DeleteMediaComment() gin.HandlerFunc {
 			return
 		}
 
-		commentID := c.Param("comment_id")
-		if commentID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "comment_id is required"})
+		reviewID := c.Param("review_id")
+		if reviewID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "review_id is required"})
 			return
 		}
 
@@ -364,47 +468,49 @@ func DeleteMediaComment() gin.HandlerFunc {
 
 		role, _ := c.Get("role")
 
-		var media models.Media
-		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		filter := bson.M{"tmdb_id": tmdbID, "review_id": reviewID}
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, filter).Decode(&review)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 			return
 		}
 
-		canDelete := false
-		for _, comment := range media.Comments {
-			if comment.CommentID == commentID {
-				if comment.UserID == userID.(string) || role == "ADMIN" {
-					canDelete = true
-					break
-				}
-			}
+		if review.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this review"})
+			return
 		}
 
-		if !canDelete {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this comment"})
+		result, err := mediaReviewCollection.DeleteOne(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		update := bson.M{
-			"$pull": bson.M{"comments": bson.M{"comment_id": commentID}},
-			"$set":  bson.M{"updated_at": time.Now()},
+		if err = mediaReviewVoteService.DeleteTarget(ctx, reviewID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update)
-		if err != nil {
+		if err = activityService.RemoveReview(ctx, reviewID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = moderationService.CloseTarget(ctx, "media_review", reviewID, userID.(string)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message": "Comment deleted successfully",
+			"message": "Review deleted successfully",
 			"result":  result,
 		})
 	}
 }
 
-func GetMediaComments() gin.HandlerFunc {
+func GetMediaReviewHistory() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 		defer cancel()
@@ -416,16 +522,34 @@ func GetMediaComments() gin.HandlerFunc {
 			return
 		}
 
-		var media models.Media
-		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		role, _ := c.Get("role")
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "review_id": c.Param("review_id")}).Decode(&review)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 			return
 		}
 
+		if review.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to view this review's history"})
+			return
+		}
+
+		history := review.EditHistory
+		if history == nil {
+			history = []models.ReviewEdit{}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"comments": media.Comments,
-			"total":    len(media.Comments),
+			"review":  review,
+			"history": history,
 		})
 	}
 }
@@ -459,91 +583,126 @@ func AddMediaRating() gin.HandlerFunc {
 			return
 		}
 
-		var media models.Media
-		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
-		if err != nil {
+		result, err := mediaRatingService.Rate(ctx, tmdbID, userID.(string), ratingRequest.Rating)
+		if err == services.ErrTitleNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
 			return
 		}
-
-		userRatedIndex := -1
-		for i, r := range media.Ratings {
-			if r.UserID == userID.(string) {
-				userRatedIndex = i
-				break
-			}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		var update bson.M
-		var newAverageRating float64
-		var newTotalRatings int
-
-		if userRatedIndex != -1 {
-			oldRating := media.Ratings[userRatedIndex].Rating
-			totalSum := media.AverageRating * float64(media.TotalRatings)
-			totalSum = totalSum - oldRating + ratingRequest.Rating
-			newAverageRating = totalSum / float64(media.TotalRatings)
-			newTotalRatings = media.TotalRatings
-
-			update = bson.M{
-				"$set": bson.M{
-					"ratings.$[elem].rating":     ratingRequest.Rating,
-					"ratings.$[elem].created_at": time.Now(),
-					"average_rating":             newAverageRating,
-					"updated_at":                 time.Now(),
-				},
-			}
-
-			opts := options.UpdateOne().SetArrayFilters([]interface{}{
-				bson.M{"elem.user_id": userID.(string)},
-			})
-
-			result, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update, opts)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
+		publishRatingUpdate(tmdbID, result)
+		recordActivity(models.Activity{
+			ActorID:   userID.(string),
+			Type:      models.ActivityRating,
+			TargetKey: mediaActivityKey(tmdbID),
+			TMDBID:    tmdbID,
+			Rating:    ratingRequest.Rating,
+		})
 
+		if result.Updated {
 			c.JSON(http.StatusOK, gin.H{
 				"message":        "Rating updated successfully",
-				"average_rating": newAverageRating,
-				"total_ratings":  newTotalRatings,
-				"result":         result,
+				"average_rating": result.AverageRating,
+				"total_ratings":  result.TotalRatings,
 			})
 			return
 		}
 
-		rating := models.Rating{
-			UserID:    userID.(string),
-			Rating:    ratingRequest.Rating,
-			CreatedAt: time.Now(),
+		c.JSON(http.StatusCreated, gin.H{
+			"message":        "Rating added successfully",
+			"average_rating": result.AverageRating,
+			"total_ratings":  result.TotalRatings,
+		})
+	}
+}
+
+func DeleteMediaRating() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
 		}
 
-		totalSum := media.AverageRating * float64(media.TotalRatings)
-		totalSum += ratingRequest.Rating
-		newTotalRatings = media.TotalRatings + 1
-		newAverageRating = totalSum / float64(newTotalRatings)
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		result, err := mediaRatingService.Remove(ctx, tmdbID, userID.(string))
+		if err == services.ErrRatingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		publishRatingUpdate(tmdbID, result)
 
-		update = bson.M{
-			"$push": bson.M{"ratings": rating},
-			"$set": bson.M{
-				"average_rating": newAverageRating,
-				"total_ratings":  newTotalRatings,
-				"updated_at":     time.Now(),
-			},
+		if err = activityService.Remove(ctx, userID.(string), models.ActivityRating, mediaActivityKey(tmdbID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, err := mediaCollection.UpdateOne(ctx, bson.M{"tmdb_id": tmdbID}, update)
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Rating deleted successfully",
+			"average_rating": result.AverageRating,
+			"total_ratings":  result.TotalRatings,
+		})
+	}
+}
+
+// publishRatingUpdate sends the new rating aggregate to live listeners
+func publishRatingUpdate(tmdbID int, result *services.RatingResult) {
+	publishMediaEvent(tmdbID, "rating.updated", "", gin.H{
+		"average_rating": result.AverageRating,
+		"total_ratings":  result.TotalRatings,
+	})
+}
+
+func GetMediaRatings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		page, limit := getPagination(c)
+		ratings, total, err := findPage[models.Rating](ctx, mediaRatingCollection, bson.M{"tmdb_id": tmdbID}, bson.D{{Key: "created_at", Value: 1}}, page, limit)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{
-			"message":        "Rating added successfully",
-			"average_rating": newAverageRating,
-			"total_ratings":  newTotalRatings,
-			"result":         result,
+		c.JSON(http.StatusOK, gin.H{
+			"ratings":        ratings,
+			"average_rating": media.AverageRating,
+			"total_ratings":  media.TotalRatings,
+			"total":          total,
+			"page":           page,
+			"limit":          limit,
 		})
 	}
 }
@@ -567,10 +726,27 @@ func GetMediaReviews() gin.HandlerFunc {
 			return
 		}
 
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, limit := getPagination(c)
+		filter := excludeAuthors(bson.M{"tmdb_id": tmdbID, "hidden": bson.M{"$ne": true}}, hidden)
+		reviews, total, err := findPage[models.Review](ctx, mediaReviewCollection, filter, getSort(c, "helpful_score"), page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"reviews":        media.Reviews,
+			"reviews":        reviews,
 			"average_rating": media.AverageRating,
 			"total_ratings":  media.TotalRatings,
+			"total":          total,
+			"page":           page,
+			"limit":          limit,
 		})
 	}
 }
@@ -0,0 +1,389 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var reportCollection *mongo.Collection = database.OpenCollection("reports")
+var moderationItemCollection *mongo.Collection = database.OpenCollection("moderation_items")
+var moderationActionCollection *mongo.Collection = database.OpenCollection("moderation_actions")
+
+var moderationService = services.NewModerationService(reportCollection, moderationItemCollection, moderationActionCollection, userCollection, map[string]services.ModerationTarget{
+	"media_review":  {Collection: mediaReviewCollection, IDField: "review_id", Remove: removeMediaReview, Announce: announceMediaReview},
+	"movie_review":  {Collection: movieReviewCollection, IDField: "review_id", Remove: removeMovieReview, Announce: announceMovieReview},
+	"media_comment": {Collection: mediaCommentCollection, IDField: "comment_id", Remove: removeMediaCommentByID, Announce: announceMediaCommentByID},
+})
+
+// RequireNotBanned stops banned users from posting or changing anything other
+// users see. Deleting their own content and votes is still allowed. It runs
+// after middleware.AuthMiddleware.
+func RequireNotBanned() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		banned, err := moderationService.IsBanned(ctx, c.GetString("userId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if banned {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Your account has been banned from posting"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func removeMediaReview(ctx context.Context, reviewID string) error {
+	if _, err := mediaReviewCollection.DeleteOne(ctx, bson.M{"review_id": reviewID}); err != nil {
+		return err
+	}
+	if err := activityService.RemoveReview(ctx, reviewID); err != nil {
+		return err
+	}
+	return mediaReviewVoteService.DeleteTarget(ctx, reviewID)
+}
+
+func removeMovieReview(ctx context.Context, reviewID string) error {
+	if _, err := movieReviewCollection.DeleteOne(ctx, bson.M{"review_id": reviewID}); err != nil {
+		return err
+	}
+	if err := activityService.RemoveReview(ctx, reviewID); err != nil {
+		return err
+	}
+	return movieReviewVoteService.DeleteTarget(ctx, reviewID)
+}
+
+func removeMediaCommentByID(ctx context.Context, commentID string) error {
+	var comment models.Comment
+	err := mediaCommentCollection.FindOne(ctx, bson.M{"comment_id": commentID}).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = removeComment(ctx, comment)
+	return err
+}
+
+// announceMediaReview adds an approved review to the author's followers'
+// feeds. A review deleted while it was held has nothing to announce.
+func announceMediaReview(ctx context.Context, reviewID string) error {
+	var review models.Review
+	err := mediaReviewCollection.FindOne(ctx, bson.M{"review_id": reviewID}).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recordActivity(reviewActivity(review, mediaActivityKey(review.TMDBID)))
+	return nil
+}
+
+func announceMovieReview(ctx context.Context, reviewID string) error {
+	var review models.Review
+	err := movieReviewCollection.FindOne(ctx, bson.M{"review_id": reviewID}).Decode(&review)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	recordActivity(reviewActivity(review, movieActivityKey(review.ImdbID)))
+	return nil
+}
+
+// announceMediaCommentByID sends the live event and reply notification an
+// approved comment was held back from. A comment deleted while it was held,
+// tombstones included, has nothing to announce. A reply whose parent has gone
+// since is still published, just without the notification.
+func announceMediaCommentByID(ctx context.Context, commentID string) error {
+	var comment models.Comment
+	err := mediaCommentCollection.FindOne(ctx, bson.M{"comment_id": commentID}).Decode(&comment)
+	if err == mongo.ErrNoDocuments || comment.Deleted {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	parentAuthorID := ""
+	if comment.ParentCommentID != "" {
+		var parent models.Comment
+		err := mediaCommentCollection.FindOne(ctx, bson.M{"comment_id": comment.ParentCommentID}).Decode(&parent)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if err == nil && !parent.Deleted {
+			parentAuthorID = parent.UserID
+		}
+	}
+
+	announceComment(comment, parentAuthorID)
+	return nil
+}
+
+func ReportMediaReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		fileReport(ctx, c, "media_review", review.ReviewID, review.UserID, review.Comment)
+	}
+}
+
+func ReportReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		movieID := c.Param("imdb_id")
+		if movieID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "movie id is empty"})
+			return
+		}
+
+		var review models.Review
+		err := movieReviewCollection.FindOne(ctx, bson.M{"imdb_id": movieID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		fileReport(ctx, c, "movie_review", review.ReviewID, review.UserID, review.Comment)
+	}
+}
+
+func ReportMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": c.Param("comment_id"), "deleted": bson.M{"$ne": true}}).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		fileReport(ctx, c, "media_comment", comment.CommentID, comment.UserID, comment.Content)
+	}
+}
+
+// fileReport validates the report body and files it against the target
+func fileReport(ctx context.Context, c *gin.Context, targetType, targetID, authorID, content string) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var reportRequest models.ReportRequest
+	if err := c.ShouldBindJSON(&reportRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := mediaValidator.Struct(&reportRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if authorID == userID.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own content"})
+		return
+	}
+
+	_, err := moderationService.Report(ctx, targetType, targetID, authorID, content, userID.(string), reportRequest.Reason, reportRequest.Details)
+	if err == services.ErrAlreadyReported {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Report submitted successfully"})
+}
+
+func GetModerationQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := c.DefaultQuery("status", string(models.ModerationStatusOpen))
+		filter := bson.M{"status": status}
+		if targetType := c.Query("target_type"); targetType != "" {
+			filter["target_type"] = targetType
+		}
+
+		page, limit := getPagination(c)
+		sort := bson.D{{Key: "report_count", Value: -1}, {Key: "created_at", Value: 1}}
+		items, total, err := findPage[models.ModerationItem](ctx, moderationItemCollection, filter, sort, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"total": total,
+			"page":  page,
+			"limit": limit,
+		})
+	}
+}
+
+func GetModerationItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		item, reports, actions, err := moderationService.Get(ctx, c.Param("item_id"))
+		if err == services.ErrModerationItemNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Moderation item not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"item":    item,
+			"reports": reports,
+			"actions": actions,
+		})
+	}
+}
+
+func ResolveModerationItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var actionRequest models.ModerationActionRequest
+		if err := c.ShouldBindJSON(&actionRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&actionRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		item, err := moderationService.Resolve(ctx, c.Param("item_id"), userID.(string), actionRequest.Action, actionRequest.Note)
+		if err == services.ErrModerationItemNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Moderation item not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		notifyModerationOutcome(item, actionRequest.Action)
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Moderation action applied successfully",
+			"item":    item,
+		})
+	}
+}
+
+func GetModerationActions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{}
+		if moderatorID := c.Query("moderator_id"); moderatorID != "" {
+			filter["moderator_id"] = moderatorID
+		}
+		if authorID := c.Query("author_id"); authorID != "" {
+			filter["author_id"] = authorID
+		}
+
+		page, limit := getPagination(c)
+		actions, total, err := findPage[models.ModerationAction](ctx, moderationActionCollection, filter, bson.D{{Key: "created_at", Value: -1}}, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"actions": actions,
+			"total":   total,
+			"page":    page,
+			"limit":   limit,
+		})
+	}
+}
+
+// notifyModerationOutcome tells the author what happened to their post. The
+// moderator is left out of the notification on purpose.
+func notifyModerationOutcome(item *models.ModerationItem, action string) {
+	var types []models.NotificationType
+	switch action {
+	case "hide":
+		types = []models.NotificationType{models.NotificationContentHidden}
+	case "delete":
+		types = []models.NotificationType{models.NotificationContentRemoved}
+	case "ban":
+		types = []models.NotificationType{models.NotificationContentRemoved, models.NotificationAccountBanned}
+	}
+
+	for _, notificationType := range types {
+		notify(services.NotificationEvent{
+			Type:       notificationType,
+			Recipients: []string{item.AuthorID},
+			TargetType: item.TargetType,
+			TargetID:   item.TargetID,
+		})
+	}
+}
@@ -3,10 +3,12 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Har2yQn78/Stream_Platform/database"
 	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -15,6 +17,9 @@ import (
 )
 
 var movieCollection *mongo.Collection = database.OpenCollection("movies")
+var movieReviewCollection *mongo.Collection = database.OpenCollection("movie_reviews")
+var movieRatingCollection *mongo.Collection = database.OpenCollection("movie_ratings")
+var movieRatingService = services.NewRatingService(movieRatingCollection, movieCollection, "imdb_id")
 
 var validate = validator.New()
 
@@ -62,10 +67,51 @@ func GetMovieById() gin.HandlerFunc {
 			return
 		}
 
+		if err = embedMovieRelations(ctx, c, &movie, c.Query("embed")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusOK, movie)
 	}
 }
 
+// embedMovieRelations fills in the reviews and ratings named in embed
+// (a comma separated list, or "all"), giving the response its pre-split shape.
+// Reviews by authors the caller blocked or muted are left out.
+func embedMovieRelations(ctx context.Context, c *gin.Context, movie *models.Movie, embed string) error {
+	if embed == "" {
+		return nil
+	}
+	hidden, err := hiddenAuthorsFor(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range strings.Split(embed, ",") {
+		wanted[strings.TrimSpace(name)] = true
+	}
+	all := wanted["all"]
+
+	filter := bson.M{"imdb_id": movie.ImdbID}
+	oldestFirst := bson.D{{Key: "created_at", Value: 1}}
+
+	if all || wanted["reviews"] {
+		visible := excludeAuthors(bson.M{"imdb_id": movie.ImdbID, "hidden": bson.M{"$ne": true}}, hidden)
+		if movie.Reviews, err = findAll[models.Review](ctx, movieReviewCollection, visible, oldestFirst); err != nil {
+			return err
+		}
+	}
+	if all || wanted["ratings"] {
+		if movie.Ratings, err = findAll[models.Rating](ctx, movieRatingCollection, filter, oldestFirst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func AddMovie() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
@@ -81,10 +127,9 @@ func AddMovie() gin.HandlerFunc {
 			return
 		}
 
-		movie.Reviews = []models.Review{}
-		movie.Ratings = []models.Rating{}
 		movie.AverageRating = 0.0
 		movie.TotalRatings = 0
+		movie.RatingSum = 0.0
 
 		result, err := movieCollection.InsertOne(ctx, movie)
 
@@ -133,45 +178,59 @@ func AddReview() gin.HandlerFunc {
 			return
 		}
 
-		review := models.Review{
-			ReviewID:  bson.NewObjectID().Hex(),
-			UserID:    userID.(string),
-			UserName:  user.FirstName + " " + user.LastName,
-			Comment:   reviewRequest.Comment,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+		screened, ok := screenContent(ctx, c, userID.(string), reviewRequest.Comment, minReviewLength)
+		if !ok {
+			return
 		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
 
-		var movie models.Movie
-		err = movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
+		review := models.Review{
+			ReviewID:      bson.NewObjectID().Hex(),
+			ImdbID:        movieID,
+			UserID:        userID.(string),
+			UserName:      user.FirstName + " " + user.LastName,
+			Comment:       text,
+			Hidden:        screened.Verdict == services.VerdictHold,
+			Spoiler:       reviewRequest.Spoiler,
+			SpoilerRanges: spoilerRanges,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}
+
+		count, err := movieCollection.CountDocuments(ctx, bson.M{"imdb_id": movieID})
 		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
 			return
 		}
 
-		for _, r := range movie.Reviews {
-			if r.UserID == userID.(string) {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "You have already reviewed this movie. Use update endpoint to modify your review."})
-				return
-			}
+		result, err := movieReviewCollection.InsertOne(ctx, review)
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You have already reviewed this movie. Use update endpoint to modify your review."})
+			return
 		}
-
-		update := bson.M{
-			"$push": bson.M{
-				"reviews": review,
-			},
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": movieID}, update)
-		if err != nil {
+		if err = holdForReview(ctx, screened, "movie_review", review.ReviewID, userID.(string), true); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if !review.Hidden {
+			recordActivity(reviewActivity(review, movieActivityKey(movieID)))
+		}
+
 		c.JSON(http.StatusCreated, gin.H{
-			"message": "Review added successfully",
-			"review":  review,
-			"result":  result,
+			"message":         "Review added successfully",
+			"review":          review,
+			"result":          result,
+			"held_for_review": review.Hidden,
 		})
 	}
 }
@@ -206,53 +265,42 @@ func UpdateReview() gin.HandlerFunc {
 			return
 		}
 
-		var movie models.Movie
-		err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
-			return
+		filter := bson.M{
+			"imdb_id":   movieID,
+			"review_id": reviewID,
+			"user_id":   userID.(string),
 		}
 
-		found := false
-		for _, review := range movie.Reviews {
-			if review.ReviewID == reviewID && review.UserID == userID.(string) {
-				found = true
-				break
-			}
+		screened, ok := screenContent(ctx, c, "", updateRequest.Comment, minReviewLength)
+		if !ok {
+			return
 		}
+		text, spoilerRanges := services.ParseSpoilers(screened.Text)
 
-		if !found {
+		var review models.Review
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := movieReviewCollection.FindOneAndUpdate(ctx, filter, reviewEditPipeline(text, spoilerRanges, updateRequest.Spoiler, userID.(string), time.Now()), opts).Decode(&review)
+		if err == mongo.ErrNoDocuments {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found or you don't have permission to update it"})
 			return
 		}
-
-		// Update the review
-		update := bson.M{
-			"$set": bson.M{
-				"reviews.$[elem].comment":    updateRequest.Comment,
-				"reviews.$[elem].updated_at": time.Now(),
-			},
-		}
-
-		opts := options.UpdateOne().SetArrayFilters([]interface{}{
-			bson.M{
-				"elem.review_id": reviewID,
-				"elem.user_id":   userID.(string),
-			},
-		})
-
-		result, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": movieID}, update, opts)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		if result.ModifiedCount == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found or you don't have permission to update it"})
+		if err = holdForReview(ctx, screened, "movie_review", reviewID, userID.(string), true); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		review.Hidden = review.Hidden || screened.Verdict == services.VerdictHold
+		refreshReviewActivity(review, movieActivityKey(movieID))
 
-		c.JSON(http.StatusOK, gin.H{"message": "Review updated successfully"})
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Review updated successfully",
+			"review":          review,
+			"held_for_review": screened.Verdict == services.VerdictHold,
+		})
 	}
 }
 
@@ -276,36 +324,36 @@ func DeleteReview() gin.HandlerFunc {
 		}
 
 		role, _ := c.Get("role")
-		var movie models.Movie
-		err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
+
+		var review models.Review
+		err := movieReviewCollection.FindOne(ctx, bson.M{"imdb_id": movieID, "review_id": reviewID}).Decode(&review)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
 			return
 		}
 
-		canDelete := false
-		for _, review := range movie.Reviews {
-			if review.ReviewID == reviewID {
-				if review.UserID == userID.(string) || role == "ADMIN" {
-					canDelete = true
-					break
-				}
-			}
+		if review.UserID != userID.(string) && !canModerate(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this review"})
+			return
 		}
 
-		if !canDelete {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have permission to delete this review"})
+		result, err := movieReviewCollection.DeleteOne(ctx, bson.M{"imdb_id": movieID, "review_id": reviewID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		update := bson.M{
-			"$pull": bson.M{
-				"reviews": bson.M{"review_id": reviewID},
-			},
+		if err = movieReviewVoteService.DeleteTarget(ctx, reviewID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": movieID}, update)
-		if err != nil {
+		if err = activityService.RemoveReview(ctx, reviewID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = moderationService.CloseTarget(ctx, "movie_review", reviewID, userID.(string)); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -345,90 +393,113 @@ func AddRating() gin.HandlerFunc {
 			return
 		}
 
-		var movie models.Movie
-		err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
-		if err != nil {
+		result, err := movieRatingService.Rate(ctx, movieID, userID.(string), ratingRequest.Rating)
+		if err == services.ErrTitleNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
 			return
 		}
-		userRatedIndex := -1
-		for i, r := range movie.Ratings {
-			if r.UserID == userID.(string) {
-				userRatedIndex = i
-				break
-			}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		var update bson.M
-		var newAverageRating float64
-		var newTotalRatings int
+		recordActivity(models.Activity{
+			ActorID:   userID.(string),
+			Type:      models.ActivityRating,
+			TargetKey: movieActivityKey(movieID),
+			ImdbID:    movieID,
+			Rating:    ratingRequest.Rating,
+		})
 
-		if userRatedIndex != -1 {
-			oldRating := movie.Ratings[userRatedIndex].Rating
-			totalSum := movie.AverageRating * float64(movie.TotalRatings)
-			totalSum = totalSum - oldRating + ratingRequest.Rating
-			newAverageRating = totalSum / float64(movie.TotalRatings)
-			newTotalRatings = movie.TotalRatings
+		if result.Updated {
+			c.JSON(http.StatusOK, gin.H{
+				"message":        "Rating updated successfully",
+				"average_rating": result.AverageRating,
+				"total_ratings":  result.TotalRatings,
+			})
+			return
+		}
 
-			update = bson.M{
-				"$set": bson.M{
-					"ratings.$[elem].rating":     ratingRequest.Rating,
-					"ratings.$[elem].created_at": time.Now(),
-					"average_rating":             newAverageRating,
-				},
-			}
+		c.JSON(http.StatusCreated, gin.H{
+			"message":        "Rating added successfully",
+			"average_rating": result.AverageRating,
+			"total_ratings":  result.TotalRatings,
+		})
+	}
+}
 
-			opts := options.UpdateOne().SetArrayFilters([]interface{}{
-				bson.M{"elem.user_id": userID.(string)},
-			})
+func DeleteRating() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-			result, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": movieID}, update, opts)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
+		movieID := c.Param("imdb_id")
+		if movieID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "movie id is empty"})
+			return
+		}
 
-			c.JSON(http.StatusOK, gin.H{
-				"message":        "Rating updated successfully",
-				"average_rating": newAverageRating,
-				"total_ratings":  newTotalRatings,
-				"result":         result,
-			})
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 			return
 		}
 
-		rating := models.Rating{
-			UserID:    userID.(string),
-			Rating:    ratingRequest.Rating,
-			CreatedAt: time.Now(),
+		result, err := movieRatingService.Remove(ctx, movieID, userID.(string))
+		if err == services.ErrRatingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = activityService.Remove(ctx, userID.(string), models.ActivityRating, movieActivityKey(movieID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		totalSum := movie.AverageRating * float64(movie.TotalRatings)
-		totalSum += ratingRequest.Rating
-		newTotalRatings = movie.TotalRatings + 1
-		newAverageRating = totalSum / float64(newTotalRatings)
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Rating deleted successfully",
+			"average_rating": result.AverageRating,
+			"total_ratings":  result.TotalRatings,
+		})
+	}
+}
+
+func GetMovieRatings() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
 
-		update = bson.M{
-			"$push": bson.M{
-				"ratings": rating,
-			},
-			"$set": bson.M{
-				"average_rating": newAverageRating,
-				"total_ratings":  newTotalRatings,
-			},
+		movieID := c.Param("imdb_id")
+		if movieID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "movie id is empty"})
+			return
 		}
 
-		result, err := movieCollection.UpdateOne(ctx, bson.M{"imdb_id": movieID}, update)
+		var movie models.Movie
+		err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			return
+		}
+
+		page, limit := getPagination(c)
+		ratings, total, err := findPage[models.Rating](ctx, movieRatingCollection, bson.M{"imdb_id": movieID}, bson.D{{Key: "created_at", Value: 1}}, page, limit)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusCreated, gin.H{
-			"message":        "Rating added successfully",
-			"average_rating": newAverageRating,
-			"total_ratings":  newTotalRatings,
-			"result":         result,
+		c.JSON(http.StatusOK, gin.H{
+			"ratings":        ratings,
+			"average_rating": movie.AverageRating,
+			"total_ratings":  movie.TotalRatings,
+			"total":          total,
+			"page":           page,
+			"limit":          limit,
 		})
 	}
 }
@@ -451,10 +522,27 @@ func GetMovieReviews() gin.HandlerFunc {
 			return
 		}
 
+		page, limit := getPagination(c)
+		hidden, err := hiddenAuthorsFor(ctx, c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter := excludeAuthors(bson.M{"imdb_id": movieID, "hidden": bson.M{"$ne": true}}, hidden)
+		reviews, total, err := findPage[models.Review](ctx, movieReviewCollection, filter, getSort(c, "helpful_score"), page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
-			"reviews":        movie.Reviews,
+			"reviews":        reviews,
 			"average_rating": movie.AverageRating,
 			"total_ratings":  movie.TotalRatings,
+			"total":          total,
+			"page":           page,
+			"limit":          limit,
 		})
 	}
 }
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var notificationCollection *mongo.Collection = database.OpenCollection("notifications")
+var notificationService = services.NewNotificationService(notificationCollection, userCollection)
+
+// notify fans an event out in the background; the request that caused it has
+// already succeeded, so a failed notification is only logged
+func notify(event services.NotificationEvent) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := notificationService.Notify(ctx, event); err != nil {
+			log.Printf("notify %s on %s %s: %v", event.Type, event.TargetType, event.TargetID, err)
+		}
+	}()
+}
+
+func GetNotifications() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		filter := bson.M{"user_id": userID.(string)}
+		if c.Query("unread") == "true" {
+			filter["read"] = false
+		}
+
+		page, limit := getPagination(c)
+		notifications, total, err := findPage[models.Notification](ctx, notificationCollection, filter, bson.D{{Key: "created_at", Value: -1}}, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		unread, err := notificationService.UnreadCount(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"notifications": notifications,
+			"unread_count":  unread,
+			"total":         total,
+			"page":          page,
+			"limit":         limit,
+		})
+	}
+}
+
+func GetUnreadNotificationCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		unread, err := notificationService.UnreadCount(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+	}
+}
+
+func MarkNotificationsRead() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var readRequest models.MarkNotificationsReadRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&readRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+				return
+			}
+		}
+
+		if err := validate.Struct(&readRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		marked, err := notificationService.MarkRead(ctx, userID.(string), readRequest.NotificationIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		unread, err := notificationService.UnreadCount(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Notifications marked as read",
+			"marked":       marked,
+			"unread_count": unread,
+		})
+	}
+}
+
+func GetNotificationPreferences() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		preferences, err := notificationService.Preferences(ctx, userID.(string))
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+	}
+}
+
+func UpdateNotificationPreferences() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var preferencesRequest models.NotificationPreferencesRequest
+		if err := c.ShouldBindJSON(&preferencesRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&preferencesRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		preferences, err := notificationService.UpdatePreferences(ctx, userID.(string), preferencesRequest.Preferences)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "Notification preferences updated successfully",
+			"preferences": preferences,
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+	// maxPage keeps (page-1)*limit well inside an int64 skip
+	maxPage = 100000
+)
+
+// getPagination reads the page and limit query parameters, falling back to
+// defaults. Both are capped, so an oversized page just comes back empty.
+func getPagination(c *gin.Context) (int64, int64) {
+	page, limit := int64(1), int64(defaultPageLimit)
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if p, err := strconv.ParseInt(pageStr, 10, 64); err == nil && p > 0 {
+			page = min(p, maxPage)
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 64); err == nil && l > 0 {
+			limit = min(l, maxPageLimit)
+		}
+	}
+
+	return page, limit
+}
+
+// getSort reads the sort query parameter (helpful, newest or oldest) and
+// returns the matching sort document; oldest first is the default
+func getSort(c *gin.Context, helpfulField string) bson.D {
+	switch c.Query("sort") {
+	case "helpful":
+		return bson.D{{Key: helpfulField, Value: -1}, {Key: "created_at", Value: -1}}
+	case "newest":
+		return bson.D{{Key: "created_at", Value: -1}}
+	default:
+		return bson.D{{Key: "created_at", Value: 1}}
+	}
+}
+
+// findPage returns one page of documents matching filter along with the total count
+func findPage[T any](ctx context.Context, collection *mongo.Collection, filter any, sort bson.D, page, limit int64) ([]T, int64, error) {
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// findAll returns every document matching filter
+func findAll[T any](ctx context.Context, collection *mongo.Collection, filter any, sort bson.D) ([]T, error) {
+	cursor, err := collection.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+	if err = cursor.All(ctx, &items); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
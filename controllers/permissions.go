@@ -0,0 +1,6 @@
+package controllers
+
+// canModerate reports whether the role may act on other users' content
+func canModerate(role any) bool {
+	return role == "ADMIN" || role == "MODERATOR"
+}
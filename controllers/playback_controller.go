@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/Har2yQn78/Stream_Platform/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var playbackSessionCollection *mongo.Collection = database.OpenCollection("playback_sessions")
+var playbackSessionService = services.NewPlaybackSessionService(playbackSessionCollection)
+
+// hideVideoURLs blanks the video URLs of media shown to anonymous callers.
+// Signed-in users ask POST /media/:tmdb_id/playback for a URL to play.
+func hideVideoURLs(c *gin.Context, media []models.Media) {
+	if _, exists := c.Get("userId"); exists {
+		return
+	}
+	for i := range media {
+		media[i].VideoURL = ""
+	}
+}
+
+// CreatePlaybackURL issues a signed, expiring URL for the caller to stream a
+// title, or an episode when season and episode are given. HLS renditions are
+// preferred when the player can play them; otherwise one of the title's
+// sources is picked by the player's capability hints, see
+// services.SelectSource. Externally hosted videos are returned as they are.
+// Each call opens a playback session, refused with 409 and the account's
+// other sessions when it is already streaming on too many devices.
+func CreatePlaybackURL() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		// The body is optional; movies don't need one
+		var playbackRequest models.PlaybackRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&playbackRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+				return
+			}
+		}
+
+		if err := mediaValidator.Struct(&playbackRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		if playbackRequest.Season != nil && media.MediaType != models.MediaTypeTV {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only shows have episodes"})
+			return
+		}
+		filter := trackScope(tmdbID, playbackRequest.Season, playbackRequest.Episode)
+
+		hasAsset := true
+		err = videoAssetCollection.FindOne(ctx, filter).Err()
+		if err == mongo.ErrNoDocuments {
+			hasAsset = false
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		response := models.PlaybackResponse{
+			TMDBID:  tmdbID,
+			Season:  playbackRequest.Season,
+			Episode: playbackRequest.Episode,
+		}
+		switch {
+		case hasAsset && services.SupportsHLS(playbackRequest.PlaybackCapabilities):
+			response.Kind = models.PlaybackHLS
+			response.URL = services.HLSMasterPath(tmdbID, playbackRequest.Season, playbackRequest.Episode)
+		case playbackRequest.Season != nil && hasAsset:
+			// Episodes have no sources to fall back on
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": "Episodes are only streamed as HLS, which the player doesn't support"})
+			return
+		case playbackRequest.Season != nil:
+			c.JSON(http.StatusNotFound, gin.H{"error": "This episode has no stream"})
+			return
+		default:
+			sources := mediaSources(media)
+			if len(sources) == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "This media has no stream"})
+				return
+			}
+			source, err := services.SelectSource(sources, playbackRequest.PlaybackCapabilities)
+			if errors.Is(err, services.ErrNoPlayableSource) {
+				c.JSON(http.StatusNotAcceptable, gin.H{"error": err.Error()})
+				return
+			}
+			response.Source = source
+			if source.StorageKey != "" {
+				response.Kind = models.PlaybackFile
+				response.URL = sourceStreamPath(tmdbID, source)
+			} else {
+				response.Kind = models.PlaybackExternal
+				response.URL = source.URL
+			}
+		}
+
+		session := models.PlaybackSession{
+			SessionID:  playbackRequest.SessionID,
+			UserID:     userID.(string),
+			TMDBID:     tmdbID,
+			Season:     playbackRequest.Season,
+			Episode:    playbackRequest.Episode,
+			DeviceName: playbackRequest.DeviceName,
+			UserAgent:  c.Request.UserAgent(),
+			IPAddress:  c.ClientIP(),
+		}
+		others, err := playbackSessionService.Start(ctx, &session)
+		if errors.Is(err, services.ErrStreamLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":           err.Error(),
+				"max_streams":     playbackSessionService.MaxStreams(),
+				"active_sessions": others,
+			})
+			return
+		}
+		if errors.Is(err, services.ErrPlaybackSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.SessionID = session.SessionID
+		response.HeartbeatInterval = int(services.PlaybackHeartbeatInterval.Seconds())
+
+		response.Subtitles, err = findAll[models.SubtitleTrack](ctx, subtitleTrackCollection, filter, trackSort)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response.AudioTracks, err = findAll[models.AudioTrack](ctx, audioTrackCollection, filter, trackSort)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		hasThumbnails := true
+		err = thumbnailTrackCollection.FindOne(ctx, filter).Err()
+		if err == mongo.ErrNoDocuments {
+			hasThumbnails = false
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if playbackRequest.Season != nil {
+			record, err := findEpisode(ctx, tmdbID, *playbackRequest.Season, *playbackRequest.Episode)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if record != nil {
+				response.Markers, response.Chapters = record.Markers, record.Chapters
+			}
+		} else {
+			response.Markers, response.Chapters = media.Markers, media.Chapters
+		}
+
+		query, expiresAt := signPlaybackQuery(c, tmdbID, userID.(string), session.SessionID)
+		// Externally hosted videos can't check a signature, but their
+		// subtitles and thumbnails are still served from here
+		if response.Kind != models.PlaybackExternal {
+			response.URL += "?" + query
+		}
+		for i := range response.Subtitles {
+			response.Subtitles[i].URL = fmt.Sprintf("/media/%d/subtitles/%s/vtt?%s", tmdbID, response.Subtitles[i].TrackID, query)
+		}
+		if hasThumbnails {
+			response.Thumbnails = services.ThumbnailsPath(tmdbID, playbackRequest.Season, playbackRequest.Episode) + "?" + query
+		}
+		if response.Kind != models.PlaybackExternal || len(response.Subtitles) > 0 || hasThumbnails {
+			response.ExpiresAt = &expiresAt
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// signPlaybackQuery signs the query that lets a playback session fetch a
+// title's streams, subtitles and thumbnails until the returned time
+func signPlaybackQuery(c *gin.Context, tmdbID int, userID, sessionID string) (string, time.Time) {
+	clientIP := ""
+	if utils.PlaybackBindsIP() {
+		clientIP = c.ClientIP()
+	}
+	expiresAt := time.Now().Add(utils.PlaybackURLTTL()).Truncate(time.Second)
+	return utils.SignPlayback(tmdbID, userID, sessionID, clientIP, expiresAt).Encode(), expiresAt
+}
+
+// RequirePlaybackSession stops streams whose playback session has ended,
+// because the player stopped sending heartbeats or another device kicked it,
+// and streams of an episode other than the session's. Subtitle tracks carry
+// their own episode, which ServeSubtitleTrack checks. It runs after
+// middleware.PlaybackAuth.
+func RequirePlaybackSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		session, err := playbackSessionService.FindActive(ctx, c.GetString("playbackSessionId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if session == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This playback session has ended"})
+			c.Abort()
+			return
+		}
+
+		if c.Param("track_id") == "" {
+			// Routes without a season serve the movie itself
+			var season, episode *int
+			if c.Param("season") != "" {
+				_, routeSeason, routeEpisode, ok := episodeParams(c)
+				if !ok {
+					c.Abort()
+					return
+				}
+				season, episode = &routeSeason, &routeEpisode
+			}
+			if !playbackCovers(session, season, episode) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "This playback URL is for another episode"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Set("playbackSession", session)
+		c.Next()
+	}
+}
+
+// playbackCovers reports whether a playback session is for the given
+// episode, or for the movie when season and episode are nil
+func playbackCovers(session *models.PlaybackSession, season, episode *int) bool {
+	same := func(a, b *int) bool {
+		return a == nil && b == nil || a != nil && b != nil && *a == *b
+	}
+	return same(session.Season, season) && same(session.Episode, episode)
+}
+
+// GetPlaybackSessions lists the devices currently streaming on the caller's account
+func GetPlaybackSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		sessions, err := playbackSessionService.Active(ctx, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"max_streams": playbackSessionService.MaxStreams(),
+			"sessions":    sessions,
+		})
+	}
+}
+
+// PlaybackHeartbeat keeps one of the caller's sessions alive and re-signs its
+// playback query, since signed URLs only last a few minutes. The player swaps
+// the query on the URLs it fetches for the new one. A 404 tells the player
+// its session has ended and it should stop.
+func PlaybackHeartbeat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		session, err := playbackSessionService.Heartbeat(ctx, userID.(string), c.Param("session_id"))
+		if errors.Is(err, services.ErrPlaybackSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		query, expiresAt := signPlaybackQuery(c, session.TMDBID, session.UserID, session.SessionID)
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, models.PlaybackHeartbeatResponse{
+			PlaybackSession:   *session,
+			PlaybackQuery:     query,
+			PlaybackExpiresAt: expiresAt,
+		})
+	}
+}
+
+// EndPlaybackSession ends one of the caller's sessions, either when a player
+// stops or to kick another device off the account
+func EndPlaybackSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		err := playbackSessionService.End(ctx, userID.(string), c.Param("session_id"))
+		if errors.Is(err, services.ErrPlaybackSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Playback session ended"})
+	}
+}
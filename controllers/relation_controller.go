@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var relationCollection *mongo.Collection = database.OpenCollection("user_relations")
+var relationService = services.NewRelationService(relationCollection)
+
+func BlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addRelation(c, models.RelationBlock)
+	}
+}
+
+func UnblockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		removeRelation(c, models.RelationBlock)
+	}
+}
+
+func MuteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		addRelation(c, models.RelationMute)
+	}
+}
+
+func UnmuteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		removeRelation(c, models.RelationMute)
+	}
+}
+
+func GetBlockedUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listRelations(c, models.RelationBlock)
+	}
+}
+
+func GetMutedUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		listRelations(c, models.RelationMute)
+	}
+}
+
+// addRelation puts the user in the route on the caller's block or mute list
+func addRelation(c *gin.Context, relationType models.RelationType) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	targetID := c.Param("user_id")
+	if targetID == userID.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot " + string(relationType) + " yourself"})
+		return
+	}
+
+	count, err := userCollection.CountDocuments(ctx, bson.M{"user_id": targetID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err = relationService.Add(ctx, userID.(string), targetID, relationType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Blocking ends any follow between the two users
+	if relationType == models.RelationBlock {
+		if err = followService.Separate(ctx, userID.(string), targetID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User added to your " + string(relationType) + " list"})
+}
+
+// removeRelation takes the user in the route off the caller's block or mute list
+func removeRelation(c *gin.Context, relationType models.RelationType) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	err := relationService.Remove(ctx, userID.(string), c.Param("user_id"), relationType)
+	if err == services.ErrRelationNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User removed from your " + string(relationType) + " list"})
+}
+
+func listRelations(c *gin.Context, relationType models.RelationType) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	relations, err := relationService.List(ctx, userID.(string), relationType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": relations, "total": len(relations)})
+}
+
+// hiddenAuthorsFor returns the users a signed-in caller blocked or muted and
+// the users who blocked them; anonymous callers get nil
+func hiddenAuthorsFor(ctx context.Context, c *gin.Context) ([]string, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return nil, nil
+	}
+	return relationService.HiddenAuthors(ctx, userID.(string))
+}
+
+// excludeAuthors narrows a listing filter so it skips the given authors
+func excludeAuthors(filter bson.M, authors []string) bson.M {
+	if len(authors) > 0 {
+		filter["user_id"] = bson.M{"$nin": authors}
+	}
+	return filter
+}
+
+// checkNotBlocked writes a 403 and returns false when either the caller or
+// the author has blocked the other
+func checkNotBlocked(ctx context.Context, c *gin.Context, userID, authorID, message string) bool {
+	blocked, err := relationService.Blocked(ctx, userID, authorID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return false
+	}
+	return true
+}
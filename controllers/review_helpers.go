@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// reviewEditHistoryLimit caps how many previous versions a review keeps
+const reviewEditHistoryLimit = 20
+
+// reviewEditPipeline replaces the review text and moves the current text into
+// edit_history in the same update. A nil spoiler keeps the current flag.
+func reviewEditPipeline(comment string, spoilerRanges []models.SpoilerRange, spoiler *bool, editedBy string, now time.Time) mongo.Pipeline {
+	previous := bson.M{
+		"comment":        "$comment",
+		"spoiler_ranges": "$spoiler_ranges",
+		"edited_by":      bson.M{"$literal": editedBy},
+		"edited_at":      now,
+	}
+
+	set := bson.M{
+		"edit_history": bson.M{"$slice": bson.A{
+			bson.M{"$concatArrays": bson.A{bson.M{"$ifNull": bson.A{"$edit_history", bson.A{}}}, bson.A{previous}}},
+			-reviewEditHistoryLimit,
+		}},
+		// $literal keeps user text starting with "$" from being read as a field path
+		"comment":        bson.M{"$literal": comment},
+		"spoiler_ranges": spoilerRangesValue(spoilerRanges),
+		"edited":         true,
+		"updated_at":     now,
+	}
+	if spoiler != nil {
+		set["spoiler"] = *spoiler
+	}
+
+	return mongo.Pipeline{{{Key: "$set", Value: set}}}
+}
+
+// spoilerRangesValue drops the field in a pipeline update when there are no ranges
+func spoilerRangesValue(ranges []models.SpoilerRange) any {
+	if len(ranges) == 0 {
+		return "$$REMOVE"
+	}
+	return ranges
+}
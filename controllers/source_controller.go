@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// mediaSources returns a title's video sources. Titles not yet migrated by
+// cmd/migrate_sources offer their single video as unsaved sources, which
+// have no ID and stream from the original endpoint. A title whose sources
+// were all deleted has an empty list and doesn't fall back.
+func mediaSources(media models.Media) []models.VideoSource {
+	if media.Sources != nil {
+		return media.Sources
+	}
+	sources := services.DefaultSources(media.VideoURL, media.VideoFile)
+	for i := range sources {
+		sources[i].SourceID = ""
+	}
+	return sources
+}
+
+// sourceStreamPath is the API path streaming a source from media storage
+func sourceStreamPath(tmdbID int, source *models.VideoSource) string {
+	if source.SourceID == "" {
+		return fmt.Sprintf("/media/%d/stream", tmdbID)
+	}
+	return fmt.Sprintf("/media/%d/sources/%s/stream", tmdbID, source.SourceID)
+}
+
+// StreamMediaSource serves one of a title's sources from media storage, with
+// support for range requests. Requests are authorised by a signed playback
+// URL, see middleware.PlaybackAuth.
+func StreamMediaSource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		for _, source := range media.Sources {
+			if source.SourceID == c.Param("source_id") && source.StorageKey != "" {
+				serveStoredObject(c, source.StorageKey)
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video source not found"})
+	}
+}
+
+// AddVideoSource adds a video source to a title, either a URL or a file in
+// media storage. A default source replaces the title's previous default.
+func AddVideoSource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var request models.AddVideoSourceRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if request.StorageKey != "" {
+			if _, err := mediaStorage.Stat(ctx, request.StorageKey); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "storage_key is not a file in media storage"})
+				return
+			}
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if media.MediaType != models.MediaTypeMovie {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Episodes of shows are streamed from their HLS renditions"})
+			return
+		}
+
+		source := models.VideoSource{
+			SourceID:   bson.NewObjectID().Hex(),
+			Label:      request.Label,
+			Width:      request.Width,
+			Height:     request.Height,
+			Codec:      request.Codec,
+			Bitrate:    request.Bitrate,
+			Language:   request.Language,
+			Container:  request.Container,
+			URL:        request.URL,
+			StorageKey: request.StorageKey,
+			Default:    request.Default,
+		}
+		if source.Container == "" {
+			source.Container = services.SourceContainer(source.URL + source.StorageKey)
+		}
+
+		// A title not yet migrated keeps its single video as sources
+		legacy := services.DefaultSources(media.VideoURL, media.VideoFile)
+
+		// A pipeline update adds the source and clears the other defaults in
+		// one step; $literal keeps the sources' strings from being read as
+		// expressions
+		existing := bson.M{"$ifNull": bson.A{"$sources", bson.M{"$literal": legacy}}}
+		if source.Default {
+			existing = bson.M{"$map": bson.M{
+				"input": existing,
+				"in":    bson.M{"$mergeObjects": bson.A{"$$this", bson.M{"default": false}}},
+			}}
+		}
+		update := bson.A{bson.M{"$set": bson.M{
+			"sources":    bson.M{"$concatArrays": bson.A{existing, bson.A{bson.M{"$literal": source}}}},
+			"updated_at": time.Now(),
+		}}}
+		filter := bson.M{"tmdb_id": tmdbID, fmt.Sprintf("sources.%d", services.MaxVideoSources-1): bson.M{"$exists": false}}
+
+		result, err := mediaCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A title can have at most %d video sources", services.MaxVideoSources)})
+			return
+		}
+
+		c.JSON(http.StatusCreated, source)
+	}
+}
+
+// DeleteVideoSource removes a video source from a title. Deleting the last
+// one leaves the title without a stream, its legacy video included. Files in
+// media storage are left in place, as other titles or uploads may share them.
+func DeleteVideoSource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		sourceID := c.Param("source_id")
+		filter := bson.M{"tmdb_id": tmdbID, "sources.source_id": sourceID}
+		update := bson.M{
+			"$pull": bson.M{"sources": bson.M{"source_id": sourceID}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		}
+		result, err := mediaCollection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video source not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Video source deleted"})
+	}
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// mediaContentTypes covers the video and playlist formats whose types
+// mime.TypeByExtension doesn't know on every system
+var mediaContentTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/x-m4v",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".aac":  "audio/aac",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".vtt":  "text/vtt",
+}
+
+// presignExpiry is how long the storage URLs clients are redirected to last
+const presignExpiry = 15 * time.Minute
+
+var mediaStorage services.Storage = newMediaStorage()
+
+func newMediaStorage() services.Storage {
+	storage, err := services.NewStorageFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return storage
+}
+
+// serveStoredObject serves a file from media storage, with support for range
+// requests. With STORAGE_PRESIGN_REDIRECT=true and a backend that can presign
+// URLs, clients are redirected to fetch the file from storage directly.
+func serveStoredObject(c *gin.Context, key string) {
+	// Streams can run far longer than a normal request, so reads from
+	// storage last as long as the client stays connected
+	ctx := c.Request.Context()
+
+	if os.Getenv("STORAGE_PRESIGN_REDIRECT") == "true" {
+		url, err := mediaStorage.Presign(ctx, key, presignExpiry)
+		if err == nil {
+			c.Header("Cache-Control", "private, no-store")
+			c.Redirect(http.StatusTemporaryRedirect, url)
+			return
+		}
+		if !errors.Is(err, services.ErrPresignNotSupported) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	content, info, err := services.OpenObject(ctx, mediaStorage, key)
+	if errors.Is(err, services.ErrObjectNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	// http.ServeContent answers Range requests with 206 and honours If-Range,
+	// If-None-Match and If-Modified-Since against these headers
+	c.Header("ETag", info.ETag)
+	c.Header("Cache-Control", "private, max-age=3600")
+	if contentType, ok := mediaContentTypes[strings.ToLower(path.Ext(key))]; ok {
+		c.Header("Content-Type", contentType)
+	}
+	http.ServeContent(c.Writer, c.Request, key, info.ModTime, content)
+}
+
+// StreamMedia serves the title's video file, with support for range requests.
+// Requests are authorised by a signed playback URL, see middleware.PlaybackAuth.
+func StreamMedia() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		if _, exists := c.Get("userId"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var media models.Media
+		err = mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+		if media.VideoFile == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This media has no stream"})
+			return
+		}
+
+		serveStoredObject(c, media.VideoFile)
+	}
+}
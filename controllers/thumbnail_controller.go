@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/Har2yQn78/Stream_Platform/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var thumbnailTrackCollection *mongo.Collection = database.OpenCollection("thumbnail_tracks")
+
+// ServeThumbnails serves the WebVTT thumbnails track of a movie or episode
+// at thumbnails.vtt and its sprite sheets next to it. Requests are
+// authorised by a signed playback URL, see middleware.PlaybackAuth.
+func ServeThumbnails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter, ok := videoAssetFilter(c)
+		if !ok {
+			return
+		}
+
+		var track models.ThumbnailTrack
+		err := thumbnailTrackCollection.FindOne(ctx, filter).Decode(&track)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This media has no thumbnails"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		requested := strings.TrimPrefix(c.Param("path"), "/")
+		if requested != services.ThumbnailsFile {
+			if !filepath.IsLocal(requested) || strings.Contains(requested, "/") || path.Ext(requested) != ".jpg" {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+				return
+			}
+			serveStoredObject(c, path.Join(track.BaseDir, requested))
+			return
+		}
+
+		vtt, err := readStoredText(ctx, path.Join(track.BaseDir, services.ThumbnailsFile))
+		if errors.Is(err, services.ErrObjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Cue URIs are relative, so the signed query is passed on to them
+		query := utils.PlaybackQuery(c.Request.URL.Query()).Encode()
+		c.Header("Cache-Control", "private, no-cache")
+		c.Data(http.StatusOK, mediaContentTypes[".vtt"], services.AppendThumbnailQuery(vtt, query))
+	}
+}
@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var titleRequestCollection *mongo.Collection = database.OpenCollection("title_requests")
+var titleRequestVoteService = services.NewVoteService(voteCollection, titleRequestCollection, "title_request", "request_id", "vote_count", "", "")
+
+// titleRequestRanking puts the most wanted titles first, oldest first on ties
+var titleRequestRanking = bson.D{{Key: "vote_count", Value: -1}, {Key: "created_at", Value: 1}}
+
+// closeTitleRequest marks the pending request for a title that has just
+// entered the library as fulfilled and tells everyone who voted for it
+func closeTitleRequest(ctx context.Context, media models.Media, resolvedBy string) error {
+	now := time.Now()
+	filter := bson.M{"tmdb_id": media.TMDBID, "media_type": media.MediaType, "status": models.TitleRequestPending}
+	update := bson.M{"$set": bson.M{
+		"status":      models.TitleRequestFulfilled,
+		"resolved_by": resolvedBy,
+		"resolved_at": now,
+		"updated_at":  now,
+	}}
+
+	var request models.TitleRequest
+	err := titleRequestCollection.FindOneAndUpdate(ctx, filter, update).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	voters, err := titleRequestVoteService.Voters(ctx, request.RequestID)
+	if err != nil {
+		return err
+	}
+	notify(services.NotificationEvent{
+		Type:       models.NotificationTitleAvailable,
+		ActorID:    resolvedBy,
+		Recipients: voters,
+		TargetType: "title_request",
+		TargetID:   request.RequestID,
+		TMDBID:     media.TMDBID,
+	})
+	return nil
+}
+
+// CreateTitleRequest asks for a TMDB title to be added. When the title is
+// already requested the caller's ask counts as an upvote on that request.
+func CreateTitleRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var titleRequest models.CreateTitleRequestRequest
+		if err := c.ShouldBindJSON(&titleRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&titleRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": titleRequest.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This title is already in the library"})
+			return
+		}
+
+		pending := bson.M{"tmdb_id": titleRequest.TMDBID, "media_type": titleRequest.MediaType, "status": models.TitleRequestPending}
+
+		var request models.TitleRequest
+		err = titleRequestCollection.FindOne(ctx, pending).Decode(&request)
+		created := err == mongo.ErrNoDocuments
+		if err != nil && !created {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if created {
+			// Only titles TMDB knows about can be requested
+			details, err := mediaFromTMDB(titleRequest.TMDBID, titleRequest.MediaType, "")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			now := time.Now()
+			request = models.TitleRequest{
+				RequestID:   bson.NewObjectID().Hex(),
+				TMDBID:      titleRequest.TMDBID,
+				MediaType:   titleRequest.MediaType,
+				Title:       details.Title,
+				Overview:    details.Overview,
+				PosterPath:  details.PosterPath,
+				ReleaseDate: details.ReleaseDate,
+				RequestedBy: userID.(string),
+				Status:      models.TitleRequestPending,
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			}
+
+			_, err = titleRequestCollection.InsertOne(ctx, request)
+			if mongo.IsDuplicateKeyError(err) {
+				// Someone requested the same title a moment ago
+				created = false
+				err = titleRequestCollection.FindOne(ctx, pending).Decode(&request)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		counts, _, err := titleRequestVoteService.Cast(ctx, request.RequestID, userID.(string), 1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		request.VoteCount = counts.Up
+
+		if !created {
+			c.JSON(http.StatusOK, gin.H{
+				"message": "This title was already requested, your vote was added",
+				"request": request,
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Title requested successfully",
+			"request": request,
+		})
+	}
+}
+
+// GetTitleRequests lists the open requests, most voted first
+func GetTitleRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		writeTitleRequestPage(ctx, c, bson.M{"status": models.TitleRequestPending})
+	}
+}
+
+func GetTitleRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var request models.TitleRequest
+		err := titleRequestCollection.FindOne(ctx, bson.M{"request_id": c.Param("request_id")}).Decode(&request)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Title request not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, request)
+	}
+}
+
+func VoteTitleRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		if !checkTitleRequestOpen(ctx, c) {
+			return
+		}
+
+		counts, _, err := titleRequestVoteService.Cast(ctx, c.Param("request_id"), userID.(string), 1)
+		respondVote(c, counts, err)
+	}
+}
+
+func ClearTitleRequestVote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		if !checkTitleRequestOpen(ctx, c) {
+			return
+		}
+
+		counts, err := titleRequestVoteService.Clear(ctx, c.Param("request_id"), userID.(string))
+		respondVote(c, counts, err)
+	}
+}
+
+// checkTitleRequestOpen writes an error and returns false unless the request
+// in the route is still pending
+func checkTitleRequestOpen(ctx context.Context, c *gin.Context) bool {
+	var request models.TitleRequest
+	err := titleRequestCollection.FindOne(ctx, bson.M{"request_id": c.Param("request_id")}).Decode(&request)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Title request not found"})
+		return false
+	}
+	if request.Status != models.TitleRequestPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This title request is no longer open"})
+		return false
+	}
+	return true
+}
+
+// GetTitleRequestQueue is the admin view of requests, ranked by votes. It
+// shows pending requests unless ?status= asks for fulfilled or rejected ones.
+func GetTitleRequestQueue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := models.TitleRequestStatus(c.DefaultQuery("status", string(models.TitleRequestPending)))
+		switch status {
+		case models.TitleRequestPending, models.TitleRequestFulfilled, models.TitleRequestRejected:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, fulfilled or rejected"})
+			return
+		}
+
+		writeTitleRequestPage(ctx, c, bson.M{"status": status})
+	}
+}
+
+func writeTitleRequestPage(ctx context.Context, c *gin.Context, filter bson.M) {
+	page, limit := getPagination(c)
+	requests, total, err := findPage[models.TitleRequest](ctx, titleRequestCollection, filter, titleRequestRanking, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// FulfilTitleRequest adds the requested title to the library with the given
+// video and notifies everyone who voted for it
+func FulfilTitleRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var fulfilRequest models.FulfilTitleRequestRequest
+		if err := c.ShouldBindJSON(&fulfilRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&fulfilRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var request models.TitleRequest
+		err := titleRequestCollection.FindOne(ctx, bson.M{"request_id": c.Param("request_id")}).Decode(&request)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Title request not found"})
+			return
+		}
+		if request.Status != models.TitleRequestPending {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This title request is no longer open"})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": request.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Media already exists in database"})
+			return
+		}
+
+		media, err := mediaFromTMDB(request.TMDBID, request.MediaType, fulfilRequest.VideoURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		media.Sources = services.DefaultSources(media.VideoURL, "")
+		media.AddedBy = userID.(string)
+		media.CreatedAt = time.Now()
+		media.UpdatedAt = time.Now()
+
+		if _, err = mediaCollection.InsertOne(ctx, media); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err = closeTitleRequest(ctx, media, userID.(string)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Title request fulfilled, media added successfully",
+			"media":   media,
+		})
+	}
+}
+
+func RejectTitleRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var rejectRequest models.RejectTitleRequestRequest
+		if err := c.ShouldBindJSON(&rejectRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&rejectRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now := time.Now()
+		filter := bson.M{"request_id": c.Param("request_id"), "status": models.TitleRequestPending}
+		update := bson.M{"$set": bson.M{
+			"status":        models.TitleRequestRejected,
+			"reject_reason": rejectRequest.Reason,
+			"resolved_by":   userID.(string),
+			"resolved_at":   now,
+			"updated_at":    now,
+		}}
+
+		var request models.TitleRequest
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := titleRequestCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&request)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No open title request with this id"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Title request rejected",
+			"request": request,
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var subtitleTrackCollection *mongo.Collection = database.OpenCollection("subtitle_tracks")
+var audioTrackCollection *mongo.Collection = database.OpenCollection("audio_tracks")
+
+// maxSubtitleSize caps subtitle uploads; a feature film's subtitles are
+// rarely more than a few hundred kilobytes
+const maxSubtitleSize = 5 << 20
+
+// trackSort puts the default track first, then the rest by label
+var trackSort = bson.D{{Key: "default", Value: -1}, {Key: "label", Value: 1}}
+
+// trackScope matches the tracks of a movie, or of one episode of a show when
+// season and episode are given
+func trackScope(tmdbID int, season, episode *int) bson.M {
+	filter := bson.M{"tmdb_id": tmdbID, "season": nil, "episode": nil}
+	if season != nil && episode != nil {
+		filter["season"] = *season
+		filter["episode"] = *episode
+	}
+	return filter
+}
+
+// trackScopeFromQuery reads the title from the route and an optional
+// ?season=&episode= from the query
+func trackScopeFromQuery(c *gin.Context) (bson.M, bool) {
+	tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+		return nil, false
+	}
+
+	seasonStr, episodeStr := c.Query("season"), c.Query("episode")
+	if seasonStr == "" && episodeStr == "" {
+		return trackScope(tmdbID, nil, nil), true
+	}
+	season, err := strconv.Atoi(seasonStr)
+	if err != nil || season < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid season"})
+		return nil, false
+	}
+	episode, err := strconv.Atoi(episodeStr)
+	if err != nil || episode < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid episode"})
+		return nil, false
+	}
+	return trackScope(tmdbID, &season, &episode), true
+}
+
+// checkTrackMedia makes sure the title a track is added to exists, and is a
+// show when the track is for an episode
+func checkTrackMedia(ctx context.Context, c *gin.Context, tmdbID int, season *int) bool {
+	var media models.Media
+	err := mediaCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID}).Decode(&media)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if season != nil && media.MediaType != models.MediaTypeTV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only shows have episodes"})
+		return false
+	}
+	return true
+}
+
+// clearOtherDefaults leaves trackID as the only default track in its scope
+func clearOtherDefaults(ctx context.Context, collection *mongo.Collection, scope bson.M, trackID string) error {
+	filter := bson.M{"track_id": bson.M{"$ne": trackID}, "default": true}
+	for key, value := range scope {
+		filter[key] = value
+	}
+	_, err := collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"default": false}})
+	return err
+}
+
+// GetSubtitleTracks lists the subtitles of a movie, or of an episode with
+// ?season=&episode=
+func GetSubtitleTracks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter, ok := trackScopeFromQuery(c)
+		if !ok {
+			return
+		}
+
+		tracks, err := findAll[models.SubtitleTrack](ctx, subtitleTrackCollection, filter, trackSort)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tracks)
+	}
+}
+
+// AddSubtitleTrack uploads an SRT or WebVTT file as a subtitle track. The
+// file is sent as multipart form data in the file field, next to the fields
+// of models.AddSubtitleTrackRequest.
+func AddSubtitleTrack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		// Leave room for the other form fields
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubtitleSize+1<<20)
+
+		var request models.AddSubtitleTrackRequest
+		if err := c.ShouldBind(&request); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "subtitle file is too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "subtitle file is required"})
+			return
+		}
+		if header.Size > maxSubtitleSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "subtitle file is too large"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxSubtitleSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		vtt, duration, err := services.ParseSubtitles(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !checkTrackMedia(ctx, c, tmdbID, request.Season) {
+			return
+		}
+
+		kind := request.Kind
+		if kind == "" {
+			kind = models.SubtitleKindSubtitles
+		}
+		track := models.SubtitleTrack{
+			TrackID:   bson.NewObjectID().Hex(),
+			TMDBID:    tmdbID,
+			Season:    request.Season,
+			Episode:   request.Episode,
+			Language:  request.Language,
+			Label:     request.Label,
+			Kind:      kind,
+			Forced:    request.Forced,
+			Default:   request.Default,
+			Duration:  duration.Seconds(),
+			CreatedBy: userID.(string),
+			CreatedAt: time.Now(),
+		}
+		track.StorageKey = path.Join("subtitles", strconv.Itoa(tmdbID), track.TrackID+".vtt")
+
+		if err := mediaStorage.Put(ctx, track.StorageKey, bytes.NewReader(vtt), int64(len(vtt))); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		_, err = subtitleTrackCollection.InsertOne(ctx, track)
+		if err != nil {
+			mediaStorage.Delete(ctx, track.StorageKey)
+			if mongo.IsDuplicateKeyError(err) {
+				c.JSON(http.StatusConflict, gin.H{"error": "A subtitle track with this label already exists"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if track.Default {
+			scope := trackScope(tmdbID, track.Season, track.Episode)
+			if err := clearOtherDefaults(ctx, subtitleTrackCollection, scope, track.TrackID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusCreated, track)
+	}
+}
+
+// DeleteSubtitleTrack removes a subtitle track and its file
+func DeleteSubtitleTrack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var track models.SubtitleTrack
+		err := subtitleTrackCollection.FindOneAndDelete(ctx, bson.M{"track_id": c.Param("track_id")}).Decode(&track)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subtitle track not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := mediaStorage.Delete(ctx, track.StorageKey); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Subtitle track deleted"})
+	}
+}
+
+// ServeSubtitleTrack serves a subtitle track's WebVTT file to players that
+// aren't using HLS. Requests are authorised by a signed playback URL for the
+// track's movie or episode, see RequirePlaybackSession.
+func ServeSubtitleTrack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		var track models.SubtitleTrack
+		err = subtitleTrackCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "track_id": c.Param("track_id")}).Decode(&track)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subtitle track not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !playbackCovers(c.MustGet("playbackSession").(*models.PlaybackSession), track.Season, track.Episode) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This playback URL is for another episode"})
+			return
+		}
+
+		serveStoredObject(c, track.StorageKey)
+	}
+}
+
+// GetAudioTracks lists the audio tracks of a movie, or of an episode with
+// ?season=&episode=
+func GetAudioTracks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter, ok := trackScopeFromQuery(c)
+		if !ok {
+			return
+		}
+
+		tracks, err := findAll[models.AudioTrack](ctx, audioTrackCollection, filter, trackSort)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tracks)
+	}
+}
+
+// AddAudioTrack describes one of the audio tracks in a title's video
+func AddAudioTrack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var request models.AddAudioTrackRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !checkTrackMedia(ctx, c, tmdbID, request.Season) {
+			return
+		}
+
+		track := models.AudioTrack{
+			TrackID:   bson.NewObjectID().Hex(),
+			TMDBID:    tmdbID,
+			Season:    request.Season,
+			Episode:   request.Episode,
+			Language:  request.Language,
+			Label:     request.Label,
+			Codec:     request.Codec,
+			Channels:  request.Channels,
+			Default:   request.Default,
+			CreatedBy: userID.(string),
+			CreatedAt: time.Now(),
+		}
+
+		if _, err := audioTrackCollection.InsertOne(ctx, track); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if track.Default {
+			scope := trackScope(tmdbID, track.Season, track.Episode)
+			if err := clearOtherDefaults(ctx, audioTrackCollection, scope, track.TrackID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		c.JSON(http.StatusCreated, track)
+	}
+}
+
+// DeleteAudioTrack removes an audio track descriptor
+func DeleteAudioTrack() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := audioTrackCollection.DeleteOne(ctx, bson.M{"track_id": c.Param("track_id")})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Audio track not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Audio track deleted"})
+	}
+}
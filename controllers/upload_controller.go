@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Uploads follow the tus 1.0 resumable upload protocol: HEAD reports the
+// offset to resume from and PATCH appends a chunk at that offset, with the
+// creation, termination, checksum and expiration extensions.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,termination,checksum,expiration"
+	// statusChecksumMismatch is the status tus uses for a chunk failing its checksum
+	statusChecksumMismatch = 460
+	// uploadSweepInterval is how often expired uploads are cleaned up
+	uploadSweepInterval = time.Hour
+)
+
+var uploadCollection *mongo.Collection = database.OpenCollection("uploads")
+var uploadService = services.NewUploadService(uploadCollection, mediaCollection, mediaStorage)
+
+// uploadExtensions are the video containers that can be uploaded
+var uploadExtensions = map[string]bool{".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mkv": true, ".ts": true}
+
+// uploadErrorStatus maps upload service errors to the statuses tus clients expect
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrUploadOffsetMismatch), errors.Is(err, services.ErrUploadCompleted):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUploadBusy):
+		return http.StatusLocked
+	case errors.Is(err, services.ErrUploadTooLarge), errors.Is(err, services.ErrUploadOverflow):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUploadChecksumMismatch):
+		return statusChecksumMismatch
+	case errors.Is(err, services.ErrUnsupportedChecksum):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// checkTusResumable writes a 412 and returns false when the client speaks
+// another tus version, or sends no version where tus requires one
+func checkTusResumable(c *gin.Context, required bool) bool {
+	version := c.GetHeader("Tus-Resumable")
+	if version == tusVersion || (version == "" && !required) {
+		return true
+	}
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Tus-Resumable must be " + tusVersion})
+	return false
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header: comma separated
+// pairs of a key and a base64 value, which may be left out
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("Upload-Metadata values must be base64")
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// bindCreateUpload reads an upload request from the tus creation headers
+// (Upload-Length, and tmdb_id and filename in Upload-Metadata) or, from
+// clients not speaking tus, a JSON body
+func bindCreateUpload(c *gin.Context, uploadRequest *models.CreateUploadRequest) error {
+	if c.GetHeader("Tus-Resumable") == "" {
+		return c.ShouldBindJSON(uploadRequest)
+	}
+
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		return errors.New("Upload-Defer-Length is not supported")
+	}
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil {
+		return errors.New("invalid Upload-Length")
+	}
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		return err
+	}
+	tmdbID, err := strconv.Atoi(metadata["tmdb_id"])
+	if err != nil {
+		return errors.New("Upload-Metadata must include tmdb_id")
+	}
+
+	uploadRequest.TMDBID = tmdbID
+	uploadRequest.Filename = metadata["filename"]
+	uploadRequest.Size = size
+	return nil
+}
+
+// setUploadHeaders writes the tus headers describing an upload's progress
+func setUploadHeaders(c *gin.Context, upload *models.Upload) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if upload.Status == models.UploadInProgress {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", "no-store")
+}
+
+// GetUploadOptions lets clients discover the protocol version, extensions and limits
+func GetUploadOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+		c.Header("Tus-Version", tusVersion)
+		c.Header("Tus-Extension", tusExtensions)
+		c.Header("Tus-Max-Size", strconv.FormatInt(uploadService.MaxSize(), 10))
+		c.Header("Tus-Checksum-Algorithm", services.ChecksumAlgorithms)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// CreateUpload starts a resumable upload of a video file for a media entry,
+// either with the tus creation extension or a JSON body. The response's
+// Location is where the chunks go.
+func CreateUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		if !checkTusResumable(c, false) {
+			return
+		}
+
+		var uploadRequest models.CreateUploadRequest
+		if err := bindCreateUpload(c, &uploadRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := mediaValidator.Struct(&uploadRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !uploadExtensions[strings.ToLower(filepath.Ext(uploadRequest.Filename))] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "filename must end in .mp4, .m4v, .mov, .webm, .mkv or .ts"})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": uploadRequest.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		upload, err := uploadService.Create(ctx, uploadRequest.TMDBID, uploadRequest.Filename, uploadRequest.Size, userID.(string))
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		setUploadHeaders(c, upload)
+		c.Header("Location", "/admin/uploads/"+upload.UploadID)
+		c.JSON(http.StatusCreated, upload)
+	}
+}
+
+// GetUpload returns an upload's progress
+func GetUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !checkTusResumable(c, c.Request.Method == http.MethodHead) {
+			return
+		}
+
+		upload, err := uploadService.Get(ctx, c.Param("upload_id"))
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		setUploadHeaders(c, upload)
+		if c.Request.Method == http.MethodHead {
+			c.Status(http.StatusOK)
+			return
+		}
+		c.JSON(http.StatusOK, upload)
+	}
+}
+
+// PatchUpload appends a chunk at Upload-Offset. An Upload-Checksum such as
+// "sha256 <base64 digest>" makes the chunk all-or-nothing. The last chunk
+// attaches the finished file to the media entry.
+func PatchUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Chunks can be large, so the request gets as long as the upload lock
+		ctx, cancel := context.WithTimeout(context.Background(), services.UploadChunkTimeout)
+		defer cancel()
+
+		if !checkTusResumable(c, true) {
+			return
+		}
+
+		if c.ContentType() != "application/offset+octet-stream" {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+			return
+		}
+
+		offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Offset"})
+			return
+		}
+
+		upload, err := uploadService.WriteChunk(ctx, c.Param("upload_id"), offset, c.Request.Body, c.GetHeader("Upload-Checksum"))
+		if upload != nil {
+			setUploadHeaders(c, upload)
+		}
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// DeleteUpload abandons an unfinished upload
+func DeleteUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if !checkTusResumable(c, false) {
+			return
+		}
+
+		if err := uploadService.Abort(ctx, c.Param("upload_id")); err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Tus-Resumable", tusVersion)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// SweepExpiredUploads deletes expired uploads and their part files every
+// uploadSweepInterval, until ctx is done
+func SweepExpiredUploads(ctx context.Context) {
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		sweepCtx, cancel := context.WithTimeout(ctx, 100*time.Second)
+		swept, err := uploadService.SweepExpired(sweepCtx)
+		cancel()
+		if err != nil {
+			log.Printf("sweeping expired uploads: %v", err)
+		} else if swept > 0 {
+			log.Printf("swept %d expired uploads", swept)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
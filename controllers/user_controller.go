@@ -33,6 +33,9 @@ func RegisterUser() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		// Everyone signs up as a user; other roles are given by an admin
+		user.Role = "USER"
+		user.Banned = false
 		validate := validator.New()
 
 		if err := validate.Struct(user); err != nil {
@@ -131,3 +134,36 @@ func LoginUser(client *mongo.Client) gin.HandlerFunc {
 	}
 
 }
+
+// SetUserRole makes a user an admin, a moderator or a plain user again. The
+// new role is in the tokens the user gets at their next login.
+func SetUserRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var roleRequest models.SetRoleRequest
+		if err := c.ShouldBindJSON(&roleRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validator.New().Struct(&roleRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		update := bson.M{"$set": bson.M{"role": roleRequest.Role, "updated_at": time.Now()}}
+		result, err := userCollection.UpdateOne(ctx, bson.M{"user_id": c.Param("user_id")}, update)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully", "role": roleRequest.Role})
+	}
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+var voteCollection *mongo.Collection = database.OpenCollection("votes")
+
+var mediaReviewVoteService = services.NewVoteService(voteCollection, mediaReviewCollection, "media_review", "review_id", "helpful_count", "unhelpful_count", "helpful_score")
+var movieReviewVoteService = services.NewVoteService(voteCollection, movieReviewCollection, "movie_review", "review_id", "helpful_count", "unhelpful_count", "helpful_score")
+var mediaCommentLikeService = services.NewVoteService(voteCollection, mediaCommentCollection, "media_comment", "comment_id", "like_count", "", "")
+
+func VoteMediaReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var voteRequest models.VoteRequest
+		if err := c.ShouldBindJSON(&voteRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := mediaValidator.Struct(&voteRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		if review.UserID == userID.(string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot vote on your own review"})
+			return
+		}
+		if !checkNotBlocked(ctx, c, userID.(string), review.UserID, "You cannot vote on this user's review") {
+			return
+		}
+
+		counts, previous, err := mediaReviewVoteService.Cast(ctx, review.ReviewID, userID.(string), voteRequest.Value)
+		// Only a new up vote is worth telling the author about
+		if err == nil && voteRequest.Value == 1 && previous != 1 {
+			notify(services.NotificationEvent{
+				Type:       models.NotificationReviewVote,
+				ActorID:    userID.(string),
+				Recipients: []string{review.UserID},
+				TargetType: "media_review",
+				TargetID:   review.ReviewID,
+				TMDBID:     review.TMDBID,
+			})
+		}
+		respondVote(c, counts, err)
+	}
+}
+
+func ClearMediaReviewVote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var review models.Review
+		err = mediaReviewCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		counts, err := mediaReviewVoteService.Clear(ctx, review.ReviewID, userID.(string))
+		respondVote(c, counts, err)
+	}
+}
+
+func VoteReview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		movieID := c.Param("imdb_id")
+		if movieID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "movie id is empty"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var voteRequest models.VoteRequest
+		if err := c.ShouldBindJSON(&voteRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&voteRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var review models.Review
+		err := movieReviewCollection.FindOne(ctx, bson.M{"imdb_id": movieID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		if review.UserID == userID.(string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot vote on your own review"})
+			return
+		}
+		if !checkNotBlocked(ctx, c, userID.(string), review.UserID, "You cannot vote on this user's review") {
+			return
+		}
+
+		counts, previous, err := movieReviewVoteService.Cast(ctx, review.ReviewID, userID.(string), voteRequest.Value)
+		if err == nil && voteRequest.Value == 1 && previous != 1 {
+			notify(services.NotificationEvent{
+				Type:       models.NotificationReviewVote,
+				ActorID:    userID.(string),
+				Recipients: []string{review.UserID},
+				TargetType: "movie_review",
+				TargetID:   review.ReviewID,
+				ImdbID:     review.ImdbID,
+			})
+		}
+		respondVote(c, counts, err)
+	}
+}
+
+func ClearReviewVote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		movieID := c.Param("imdb_id")
+		if movieID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "movie id is empty"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var review models.Review
+		err := movieReviewCollection.FindOne(ctx, bson.M{"imdb_id": movieID, "review_id": c.Param("review_id")}).Decode(&review)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+
+		counts, err := movieReviewVoteService.Clear(ctx, review.ReviewID, userID.(string))
+		respondVote(c, counts, err)
+	}
+}
+
+func LikeMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": c.Param("comment_id"), "deleted": bson.M{"$ne": true}}).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		if comment.UserID == userID.(string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot like your own comment"})
+			return
+		}
+		if !checkNotBlocked(ctx, c, userID.(string), comment.UserID, "You cannot like this user's comment") {
+			return
+		}
+
+		counts, previous, err := mediaCommentLikeService.Cast(ctx, comment.CommentID, userID.(string), 1)
+		if err == nil && previous != 1 {
+			notify(services.NotificationEvent{
+				Type:       models.NotificationCommentLike,
+				ActorID:    userID.(string),
+				Recipients: []string{comment.UserID},
+				TargetType: "media_comment",
+				TargetID:   comment.CommentID,
+				TMDBID:     comment.TMDBID,
+			})
+		}
+		respondVote(c, counts, err)
+	}
+}
+
+func UnlikeMediaComment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbIDStr := c.Param("tmdb_id")
+		tmdbID, err := strconv.Atoi(tmdbIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var comment models.Comment
+		err = mediaCommentCollection.FindOne(ctx, bson.M{"tmdb_id": tmdbID, "comment_id": c.Param("comment_id")}).Decode(&comment)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+			return
+		}
+
+		counts, err := mediaCommentLikeService.Clear(ctx, comment.CommentID, userID.(string))
+		respondVote(c, counts, err)
+	}
+}
+
+// respondVote writes the result of a vote change
+func respondVote(c *gin.Context, counts *services.VoteCounts, err error) {
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Vote recorded successfully",
+			"votes":   counts,
+		})
+	case services.ErrVoteNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vote not found"})
+	case services.ErrVoteTargetNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vote target not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/Har2yQn78/Stream_Platform/services"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// watchPartyTTL is how long a party survives after its last activity
+	watchPartyTTL = 24 * time.Hour
+	// inviteCodeAlphabet leaves out characters that are easy to misread
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	inviteCodeLength   = 8
+	// maxPartyFrameBytes caps the size of one incoming WebSocket frame
+	maxPartyFrameBytes = 4096
+)
+
+var watchPartyCollection *mongo.Collection = database.OpenCollection("watch_parties")
+var watchPartyTicketCollection *mongo.Collection = database.OpenCollection("watch_party_tickets")
+var watchPartyHub = services.NewWatchPartyHub(saveWatchPartySnapshot)
+var watchPartyTicketService = services.NewWatchPartyTicketService(services.NewMongoWatchPartyTicketStore(watchPartyTicketCollection))
+
+// saveWatchPartySnapshot persists host and playback changes so a room can be
+// resumed after everyone has left. Each save pushes the party's expiry back,
+// so a party in use isn't removed mid-session.
+func saveWatchPartySnapshot(snapshot services.PartySnapshot) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{"$set": bson.M{
+		"host_id":    snapshot.HostID,
+		"playing":    snapshot.Playing,
+		"position":   snapshot.Position,
+		"updated_at": now,
+		"expires_at": now.Add(watchPartyTTL),
+	}}
+	if _, err := watchPartyCollection.UpdateOne(ctx, bson.M{"party_id": snapshot.PartyID}, update); err != nil {
+		log.Printf("saving watch party %s: %v", snapshot.PartyID, err)
+	}
+}
+
+// newInviteCode draws a code from the 32 letter alphabet; five random bits pick each letter
+func newInviteCode() (string, error) {
+	code := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	for i, b := range code {
+		code[i] = inviteCodeAlphabet[b&31]
+	}
+	return string(code), nil
+}
+
+func CreateWatchParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var partyRequest models.CreateWatchPartyRequest
+		if err := c.ShouldBindJSON(&partyRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&partyRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": partyRequest.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		now := time.Now()
+		party := models.WatchParty{
+			PartyID:   bson.NewObjectID().Hex(),
+			TMDBID:    partyRequest.TMDBID,
+			HostID:    userID.(string),
+			CreatedBy: userID.(string),
+			CreatedAt: now,
+			UpdatedAt: now,
+			ExpiresAt: now.Add(watchPartyTTL),
+		}
+
+		// Invite codes are random; retry the rare collision with a live party
+		for attempt := 0; attempt < 3; attempt++ {
+			if party.InviteCode, err = newInviteCode(); err != nil {
+				break
+			}
+			_, err = watchPartyCollection.InsertOne(ctx, party)
+			if !mongo.IsDuplicateKeyError(err) {
+				break
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"message": "Watch party created successfully",
+			"party":   party,
+		})
+	}
+}
+
+func GetWatchParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var party models.WatchParty
+		err := watchPartyCollection.FindOne(ctx, bson.M{"invite_code": strings.ToUpper(c.Param("invite_code"))}).Decode(&party)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watch party not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"party":   party,
+			"members": watchPartyHub.Members(party.PartyID),
+		})
+	}
+}
+
+// CreateWatchPartyTicket issues a single-use ticket, valid for 30 seconds,
+// for the caller's socket to join a party with
+func CreateWatchPartyTicket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var party models.WatchParty
+		err := watchPartyCollection.FindOne(ctx, bson.M{"invite_code": strings.ToUpper(c.Param("invite_code"))}).Decode(&party)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watch party not found"})
+			return
+		}
+
+		ticket, expiresAt, err := watchPartyTicketService.Issue(ctx, party.PartyID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_at": expiresAt})
+	}
+}
+
+// JoinWatchParty upgrades to a WebSocket and relays the party's messages. The
+// socket authenticates with a ticket from CreateWatchPartyTicket in the
+// ticket query parameter, and only origins listed in WS_ALLOWED_ORIGINS (comma
+// separated) may connect. The ticket is only used up once the origin has been
+// accepted; a refused handshake answers 403. See services.PartyMessage for the
+// frame format.
+func JoinWatchParty() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		var party models.WatchParty
+		err := watchPartyCollection.FindOne(ctx, bson.M{"invite_code": strings.ToUpper(c.Param("invite_code"))}).Decode(&party)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Watch party not found"})
+			return
+		}
+
+		// A room picked up again starts paused where it was left
+		saved := services.PartySnapshot{
+			PartyID:  party.PartyID,
+			HostID:   party.HostID,
+			Position: party.Position,
+		}
+
+		// Sockets authenticate with a ticket rather than cookies, so the
+		// origin check is defence in depth
+		checkOrigin := services.WebSocketOriginCheck(os.Getenv("WS_ALLOWED_ORIGINS"))
+		var user models.User
+		server := websocket.Server{
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				if err := checkOrigin(config, r); err != nil {
+					return err
+				}
+				userID, err := watchPartyTicketService.Redeem(ctx, party.PartyID, r.URL.Query().Get("ticket"))
+				if err != nil {
+					if !errors.Is(err, services.ErrInvalidTicket) {
+						log.Printf("redeeming watch party ticket for %s: %v", party.PartyID, err)
+					}
+					return err
+				}
+				if err = userCollection.FindOne(ctx, bson.M{"user_id": userID}).Decode(&user); err != nil {
+					log.Printf("finding watch party user %s: %v", userID, err)
+					return err
+				}
+				return nil
+			},
+			Handler: func(ws *websocket.Conn) {
+				ws.MaxPayloadBytes = maxPartyFrameBytes
+				userName := user.FirstName + " " + user.LastName
+				services.RelayWatchParty(ws, watchPartyHub.Join(saved, user.UserID, userName, !user.Banned))
+			},
+		}
+		server.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var watchlistCollection *mongo.Collection = database.OpenCollection("watchlist")
+
+func AddToWatchlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		var watchlistRequest models.AddWatchlistRequest
+		if err := c.ShouldBindJSON(&watchlistRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+
+		if err := validate.Struct(&watchlistRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		count, err := mediaCollection.CountDocuments(ctx, bson.M{"tmdb_id": watchlistRequest.TMDBID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Media not found"})
+			return
+		}
+
+		filter := bson.M{"user_id": userID.(string), "tmdb_id": watchlistRequest.TMDBID}
+		update := bson.M{"$setOnInsert": bson.M{"added_at": time.Now()}}
+		result, err := watchlistCollection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if result == nil || result.UpsertedCount == 0 {
+			c.JSON(http.StatusOK, gin.H{"message": "Title is already on your watchlist"})
+			return
+		}
+
+		recordActivity(models.Activity{
+			ActorID:   userID.(string),
+			Type:      models.ActivityWatchlist,
+			TargetKey: mediaActivityKey(watchlistRequest.TMDBID),
+			TMDBID:    watchlistRequest.TMDBID,
+		})
+
+		c.JSON(http.StatusCreated, gin.H{"message": "Title added to your watchlist"})
+	}
+}
+
+func RemoveFromWatchlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			return
+		}
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		result, err := watchlistCollection.DeleteOne(ctx, bson.M{"user_id": userID.(string), "tmdb_id": tmdbID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Title is not on your watchlist"})
+			return
+		}
+
+		if err = activityService.Remove(ctx, userID.(string), models.ActivityWatchlist, mediaActivityKey(tmdbID)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Title removed from your watchlist"})
+	}
+}
+
+// GetWatchlist returns the caller's watchlist, newest additions first, with
+// each title's media document attached
+func GetWatchlist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userID, exists := c.Get("userId")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+			return
+		}
+
+		page, limit := getPagination(c)
+		entries, total, err := findPage[models.WatchlistEntry](ctx, watchlistCollection, bson.M{"user_id": userID.(string)}, bson.D{{Key: "added_at", Value: -1}}, page, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		tmdbIDs := make([]int, 0, len(entries))
+		for _, entry := range entries {
+			tmdbIDs = append(tmdbIDs, entry.TMDBID)
+		}
+		media, err := mediaByTMDBID(ctx, tmdbIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range entries {
+			entries[i].Media = media[entries[i].TMDBID]
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"watchlist": entries,
+			"total":     total,
+			"page":      page,
+			"limit":     limit,
+		})
+	}
+}
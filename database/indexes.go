@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// collectionIndexes lists the indexes each collection needs, keyed by collection name
+var collectionIndexes = map[string][]mongo.IndexModel{
+	"media_reviews": {
+		{Keys: bson.D{{Key: "review_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "helpful_score", Value: -1}}},
+	},
+	"media_comments": {
+		{Keys: bson.D{{Key: "comment_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "parent_comment_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "parent_comment_id", Value: 1}, {Key: "like_count", Value: -1}}},
+		{Keys: bson.D{{Key: "root_comment_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"media_ratings": {
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"movie_reviews": {
+		{Keys: bson.D{{Key: "review_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "helpful_score", Value: -1}}},
+	},
+	"movie_ratings": {
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"reports": {
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "reporter_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "created_at", Value: 1}}},
+	},
+	"moderation_items": {
+		{Keys: bson.D{{Key: "item_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "report_count", Value: -1}, {Key: "created_at", Value: 1}}},
+	},
+	"moderation_actions": {
+		{Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "moderator_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	},
+	"votes": {
+		{Keys: bson.D{{Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"user_relations": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "target_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "target_id", Value: 1}, {Key: "type", Value: 1}}},
+	},
+	"notifications": {
+		{Keys: bson.D{{Key: "notification_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "type", Value: 1}, {Key: "target_type", Value: 1}, {Key: "target_id", Value: 1}, {Key: "actor_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "read", Value: 1}, {Key: "created_at", Value: -1}}},
+		// Notifications are deleted once expires_at has passed
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"episodes": {
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"follows": {
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "followee_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"activities": {
+		{Keys: bson.D{{Key: "activity_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "type", Value: 1}, {Key: "target_key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "activity_id", Value: -1}}},
+		{Keys: bson.D{{Key: "review_id", Value: 1}}, Options: options.Index().SetSparse(true)},
+	},
+	"watchlist": {
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tmdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "added_at", Value: -1}}},
+	},
+	"lists": {
+		{Keys: bson.D{{Key: "list_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "like_count", Value: -1}, {Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "visibility", Value: 1}, {Key: "created_at", Value: -1}}},
+	},
+	"playback_sessions": {
+		{Keys: bson.D{{Key: "session_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "expires_at", Value: 1}}},
+		// Ended sessions are only filtered out by queries; this clears them away
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"thumbnail_tracks": {
+		{Keys: bson.D{{Key: "track_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// One thumbnails track per movie or episode
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"title_requests": {
+		{Keys: bson.D{{Key: "request_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// One open request per title; fulfilled and rejected ones don't count
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "media_type", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "pending"})},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "vote_count", Value: -1}, {Key: "created_at", Value: 1}}},
+	},
+	"uploads": {
+		{Keys: bson.D{{Key: "upload_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Expired uploads are swept along with their part files, so no TTL
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expires_at", Value: 1}}},
+	},
+	"subtitle_tracks": {
+		{Keys: bson.D{{Key: "track_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Labels are what viewers pick from, so they can't repeat for a movie or episode
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}, {Key: "label", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"audio_tracks": {
+		{Keys: bson.D{{Key: "track_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}}},
+	},
+	"video_assets": {
+		{Keys: bson.D{{Key: "asset_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// One asset per movie or episode; movies have neither season nor episode
+		{Keys: bson.D{{Key: "tmdb_id", Value: 1}, {Key: "season", Value: 1}, {Key: "episode", Value: 1}}, Options: options.Index().SetUnique(true)},
+	},
+	"watch_parties": {
+		{Keys: bson.D{{Key: "party_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "invite_code", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+	"watch_party_tickets": {
+		{Keys: bson.D{{Key: "ticket_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		// Redeem checks expires_at itself; the TTL only clears out unused tickets
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	},
+}
+
+// EnsureIndexes creates any missing indexes; existing ones are left untouched
+func EnsureIndexes(ctx context.Context) error {
+	for name, indexes := range collectionIndexes {
+		if _, err := OpenCollection(name).Indexes().CreateMany(ctx, indexes); err != nil {
+			return fmt.Errorf("creating indexes on %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,27 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"time"
 
+	"github.com/Har2yQn78/Stream_Platform/controllers"
+	"github.com/Har2yQn78/Stream_Platform/database"
 	"github.com/Har2yQn78/Stream_Platform/routes"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	if err := database.EnsureIndexes(ctx); err != nil {
+		log.Fatal(err)
+	}
+
+	go controllers.SweepExpiredUploads(context.Background())
+
 	router := gin.Default()
 
 	router.GET("/hello", func(c *gin.Context) {
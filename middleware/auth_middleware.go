@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/Har2yQn78/Stream_Platform/utils"
 	"github.com/gin-gonic/gin"
@@ -33,3 +35,63 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth identifies the caller when a valid bearer token is sent and
+// lets anonymous requests through, so public listings can be personalised
+func OptionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.Header.Get("Authorization"), "Bearer ") {
+			c.Next()
+			return
+		}
+
+		token, err := utils.GetAccessToken(c)
+		if err == nil && token != "" {
+			if claims, err := utils.ValidateToken(token); err == nil {
+				c.Set("userId", claims.UserId)
+				c.Set("role", claims.Role)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole only lets requests through when AuthMiddleware put one of the given roles on the context
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		c.Abort()
+	}
+}
+
+// PlaybackAuth lets requests through that carry a valid signed playback URL
+// for the title in the route, as issued by POST /media/:tmdb_id/playback.
+// Video players can't send bearer tokens, so the URL is the credential.
+func PlaybackAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tmdbID, err := strconv.Atoi(c.Param("tmdb_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid TMDB ID"})
+			c.Abort()
+			return
+		}
+
+		userID, sessionID, err := utils.VerifyPlayback(tmdbID, c.Request.URL.Query(), c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		c.Set("userId", userID)
+		c.Set("playbackSessionId", sessionID)
+		c.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Episode holds what is known about one episode of a show beyond its video:
+// its runtime from TMDB, and the markers and chapters set by admins
+type Episode struct {
+	TMDBID  int `bson:"tmdb_id" json:"tmdb_id"`
+	Season  int `bson:"season" json:"season"`
+	Episode int `bson:"episode" json:"episode"`
+	// Runtime is in minutes, like Media.Runtime
+	Runtime   int       `bson:"runtime,omitempty" json:"runtime,omitempty"`
+	Markers   []Marker  `bson:"markers,omitempty" json:"markers,omitempty"`
+	Chapters  []Chapter `bson:"chapters,omitempty" json:"chapters,omitempty"`
+	UpdatedBy string    `bson:"updated_by" json:"-"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// Follow records that FollowerID follows FolloweeID
+type Follow struct {
+	FollowerID string    `bson:"follower_id" json:"follower_id"`
+	FolloweeID string    `bson:"followee_id" json:"followee_id"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+type ActivityType string
+
+const (
+	ActivityReview    ActivityType = "review"
+	ActivityRating    ActivityType = "rating"
+	ActivityWatchlist ActivityType = "watchlist"
+)
+
+// Activity is one entry in the followers' feeds. There is at most one per
+// user, type and title; repeating the action moves it to the top of the feed.
+type Activity struct {
+	ActivityID string       `bson:"activity_id" json:"activity_id"`
+	ActorID    string       `bson:"actor_id" json:"actor_id"`
+	ActorName  string       `bson:"actor_name" json:"actor_name"`
+	Type       ActivityType `bson:"type" json:"type"`
+	// TargetKey identifies the title across media and movies, e.g. "media:550"
+	TargetKey string    `bson:"target_key" json:"-"`
+	TMDBID    int       `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	ImdbID    string    `bson:"imdb_id,omitempty" json:"imdb_id,omitempty"`
+	ReviewID  string    `bson:"review_id,omitempty" json:"review_id,omitempty"`
+	Excerpt   string    `bson:"excerpt,omitempty" json:"excerpt,omitempty"`
+	Rating    float64   `bson:"rating,omitempty" json:"rating,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// PrivacySettings keep a user's activity out of other users' feeds and profile stats
+type PrivacySettings struct {
+	HideReviews   bool `bson:"hide_reviews" json:"hide_reviews"`
+	HideRatings   bool `bson:"hide_ratings" json:"hide_ratings"`
+	HideWatchlist bool `bson:"hide_watchlist" json:"hide_watchlist"`
+}
+
+// Hides reports whether the settings keep this kind of activity private
+func (p PrivacySettings) Hides(activityType ActivityType) bool {
+	switch activityType {
+	case ActivityReview:
+		return p.HideReviews
+	case ActivityRating:
+		return p.HideRatings
+	case ActivityWatchlist:
+		return p.HideWatchlist
+	}
+	return false
+}
+
+// UserProfile is the public view of a user
+type UserProfile struct {
+	UserID    string           `json:"user_id"`
+	FirstName string           `json:"first_name"`
+	LastName  string           `json:"last_name"`
+	CreatedAt time.Time        `json:"created_at"`
+	Stats     map[string]int64 `json:"stats"`
+	Following bool             `json:"following"`
+}
+
+type UpdatePrivacyRequest struct {
+	HideReviews   *bool `json:"hide_reviews"`
+	HideRatings   *bool `json:"hide_ratings"`
+	HideWatchlist *bool `json:"hide_watchlist"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+type ListVisibility string
+
+const (
+	// ListPublic lists show up in browse and on the owner's profile
+	ListPublic ListVisibility = "public"
+	// ListUnlisted lists are reachable by anyone with the link but never listed
+	ListUnlisted ListVisibility = "unlisted"
+	ListPrivate  ListVisibility = "private"
+)
+
+// MediaList is a named, ordered collection of media curated by a user. Items
+// keep the order the owner gave them.
+type MediaList struct {
+	ListID      string         `bson:"list_id" json:"list_id"`
+	UserID      string         `bson:"user_id" json:"user_id"`
+	UserName    string         `bson:"user_name" json:"user_name"`
+	Name        string         `bson:"name" json:"name"`
+	Description string         `bson:"description" json:"description"`
+	Visibility  ListVisibility `bson:"visibility" json:"visibility"`
+	Items       []ListItem     `bson:"items" json:"items"`
+	ItemCount   int            `bson:"item_count" json:"item_count"`
+	LikeCount   int            `bson:"like_count" json:"like_count"`
+	CreatedAt   time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+type ListItem struct {
+	TMDBID  int       `bson:"tmdb_id" json:"tmdb_id"`
+	Note    string    `bson:"note,omitempty" json:"note,omitempty"`
+	AddedAt time.Time `bson:"added_at" json:"added_at"`
+	Media   *Media    `bson:"-" json:"media,omitempty"`
+}
+
+type CreateListRequest struct {
+	Name        string         `json:"name" validate:"required,min=1,max=100"`
+	Description string         `json:"description" validate:"max=1000"`
+	Visibility  ListVisibility `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+}
+
+type UpdateListRequest struct {
+	Name        *string         `json:"name" validate:"omitempty,min=1,max=100"`
+	Description *string         `json:"description" validate:"omitempty,max=1000"`
+	Visibility  *ListVisibility `json:"visibility" validate:"omitempty,oneof=public unlisted private"`
+}
+
+type AddListItemRequest struct {
+	TMDBID int    `json:"tmdb_id" validate:"required"`
+	Note   string `json:"note" validate:"max=500"`
+	// Position is the zero based index to insert at; the item goes last when it is missing
+	Position *int `json:"position" validate:"omitempty,min=0"`
+}
+
+type UpdateListItemRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+// ReorderListRequest gives every item of the list in its new order
+type ReorderListRequest struct {
+	TMDBIDs []int `json:"tmdb_ids" validate:"required"`
+}
@@ -0,0 +1,29 @@
+package models
+
+type MarkerType string
+
+const (
+	MarkerIntro   MarkerType = "intro"
+	MarkerRecap   MarkerType = "recap"
+	MarkerCredits MarkerType = "credits"
+)
+
+// Marker is a stretch of a video players can offer to skip, such as the
+// intro. Start and End are in seconds from the beginning of the video.
+type Marker struct {
+	Type  MarkerType `bson:"type" json:"type" validate:"required,oneof=intro recap credits"`
+	Start float64    `bson:"start" json:"start" validate:"min=0"`
+	End   float64    `bson:"end" json:"end" validate:"gtfield=Start"`
+}
+
+// Chapter is a named point in a video, running until the next chapter starts
+type Chapter struct {
+	Title string  `bson:"title" json:"title" validate:"required,max=200"`
+	Start float64 `bson:"start" json:"start" validate:"min=0"`
+}
+
+// SetMarkersRequest replaces all the markers and chapters of a movie or episode
+type SetMarkersRequest struct {
+	Markers  []Marker  `json:"markers" validate:"max=10,dive"`
+	Chapters []Chapter `json:"chapters" validate:"max=200,dive"`
+}
@@ -14,12 +14,34 @@ const (
 )
 
 type Comment struct {
-	CommentID string    `bson:"comment_id" json:"comment_id"`
-	UserID    string    `bson:"user_id" json:"user_id" validate:"required"`
-	UserName  string    `bson:"user_name" json:"user_name" validate:"required"`
-	Content   string    `bson:"content" json:"content" validate:"required,min=1,max=500"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	CommentID       string    `bson:"comment_id" json:"comment_id"`
+	TMDBID          int       `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	ParentCommentID string    `bson:"parent_comment_id,omitempty" json:"parent_comment_id,omitempty"`
+	RootCommentID   string    `bson:"root_comment_id,omitempty" json:"root_comment_id,omitempty"`
+	Depth           int       `bson:"depth" json:"depth"`
+	UserID          string    `bson:"user_id" json:"user_id" validate:"required"`
+	UserName        string    `bson:"user_name" json:"user_name" validate:"required"`
+	Content         string    `bson:"content" json:"content" validate:"required,min=1,max=500"`
+	ReplyCount      int       `bson:"reply_count" json:"reply_count"`
+	LikeCount       int       `bson:"like_count" json:"like_count"`
+	Edited          bool      `bson:"edited" json:"edited"`
+	Deleted         bool      `bson:"deleted" json:"deleted"`
+	Hidden          bool      `bson:"hidden" json:"hidden"`
+	CreatedAt       time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+
+	Spoiler       bool           `bson:"spoiler" json:"spoiler"`
+	SpoilerRanges []SpoilerRange `bson:"spoiler_ranges,omitempty" json:"spoiler_ranges,omitempty"`
+	// Season and Episode tie a TV comment to the point in the show it discusses
+	Season  *int `bson:"season,omitempty" json:"season,omitempty"`
+	Episode *int `bson:"episode,omitempty" json:"episode,omitempty"`
+}
+
+// CommentThread is a top-level comment with the first page of its replies
+type CommentThread struct {
+	Comment
+	Replies      []Comment `json:"replies"`
+	TotalReplies int64     `json:"total_replies"`
 }
 
 type Media struct {
@@ -32,8 +54,16 @@ type Media struct {
 	Overview     string `bson:"overview" json:"overview"`
 	PosterPath   string `bson:"poster_path" json:"poster_path"`
 	BackdropPath string `bson:"backdrop_path" json:"backdrop_path"`
-	VideoURL     string `bson:"video_url" json:"video_url" validate:"required,url"`
-	ReleaseDate  string `bson:"release_date" json:"release_date"`
+	// VideoURL and VideoFile are the single video titles had before Sources;
+	// cmd/migrate_sources turns them into a default source
+	VideoURL    string `bson:"video_url" json:"video_url" validate:"omitempty,url"`
+	ReleaseDate string `bson:"release_date" json:"release_date"`
+	// VideoFile is the video's path under the media storage directory. It is
+	// served by the stream endpoint and never shown to clients.
+	VideoFile string `bson:"video_file,omitempty" json:"-"`
+	// Sources are the encodings of the video to choose from. An empty list
+	// means the title has none; titles from before sources have no list.
+	Sources []VideoSource `bson:"sources" json:"sources,omitempty"`
 
 	Genres []Genre `bson:"genres" json:"genres"`
 
@@ -42,12 +72,20 @@ type Media struct {
 	InProduction     bool `bson:"in_production,omitempty" json:"in_production,omitempty"`
 
 	Runtime int `bson:"runtime,omitempty" json:"runtime,omitempty"`
+	// Markers and Chapters are set for movies; episodes keep theirs in Episode
+	Markers  []Marker  `bson:"markers,omitempty" json:"markers,omitempty"`
+	Chapters []Chapter `bson:"chapters,omitempty" json:"chapters,omitempty"`
 
-	Reviews       []Review  `bson:"reviews" json:"reviews"`
-	Comments      []Comment `bson:"comments" json:"comments"`
-	Ratings       []Rating  `bson:"ratings" json:"ratings"`
+	// Reviews, comments and ratings live in their own collections and are
+	// only filled in when a handler embeds them into the response. The keys
+	// are always rendered, as before the split; an embedded relation without
+	// items is an empty list rather than null.
+	Reviews       []Review  `bson:"-" json:"reviews"`
+	Comments      []Comment `bson:"-" json:"comments"`
+	Ratings       []Rating  `bson:"-" json:"ratings"`
 	AverageRating float64   `bson:"average_rating" json:"average_rating"`
 	TotalRatings  int       `bson:"total_ratings" json:"total_ratings"`
+	RatingSum     float64   `bson:"rating_sum" json:"-"`
 
 	Ranking   Ranking   `bson:"ranking,omitempty" json:"ranking,omitempty"`
 	AddedBy   string    `bson:"added_by" json:"added_by"`
@@ -58,13 +96,19 @@ type Media struct {
 type AddMediaRequest struct {
 	TMDBID    int       `json:"tmdb_id" validate:"required"`
 	MediaType MediaType `json:"media_type" validate:"required,oneof=movie tv"`
-	VideoURL  string    `json:"video_url" validate:"required,url"`
+	VideoURL  string    `json:"video_url" validate:"required_without=VideoFile,omitempty,url"`
+	VideoFile string    `json:"video_file" validate:"omitempty,max=1024"`
 }
 
 type AddCommentRequest struct {
-	Content string `json:"content" validate:"required,min=1,max=500"`
+	Content         string `json:"content" validate:"required,min=1,max=500"`
+	ParentCommentID string `json:"parent_comment_id"`
+	Spoiler         bool   `json:"spoiler"`
+	Season          *int   `json:"season" validate:"omitempty,min=0"`
+	Episode         *int   `json:"episode" validate:"omitempty,min=1,excluded_without=Season"`
 }
 
 type UpdateCommentRequest struct {
 	Content string `json:"content" validate:"required,min=1,max=500"`
+	Spoiler *bool  `json:"spoiler"`
 }
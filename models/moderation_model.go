@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+type ModerationStatus string
+
+const (
+	ModerationStatusOpen     ModerationStatus = "open"
+	ModerationStatusApproved ModerationStatus = "approved"
+	ModerationStatusHidden   ModerationStatus = "hidden"
+	ModerationStatusDeleted  ModerationStatus = "deleted"
+	ModerationStatusBanned   ModerationStatus = "banned"
+)
+
+// Report is one user's flag on a review or comment
+type Report struct {
+	ReportID   string    `bson:"report_id" json:"report_id"`
+	ItemID     string    `bson:"item_id" json:"item_id"`
+	TargetType string    `bson:"target_type" json:"target_type"`
+	TargetID   string    `bson:"target_id" json:"target_id"`
+	ReporterID string    `bson:"reporter_id" json:"reporter_id"`
+	Reason     string    `bson:"reason" json:"reason"`
+	Details    string    `bson:"details,omitempty" json:"details,omitempty"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+}
+
+// ModerationItem groups every report on one review or comment into a single
+// queue entry. Announce is set while held content waits for approval before
+// anyone was told about it.
+type ModerationItem struct {
+	ItemID       string           `bson:"item_id" json:"item_id"`
+	TargetType   string           `bson:"target_type" json:"target_type"`
+	TargetID     string           `bson:"target_id" json:"target_id"`
+	AuthorID     string           `bson:"author_id" json:"author_id"`
+	Content      string           `bson:"content" json:"content"`
+	Reasons      []string         `bson:"reasons" json:"reasons"`
+	ReportCount  int              `bson:"report_count" json:"report_count"`
+	TotalReports int              `bson:"total_reports" json:"total_reports"`
+	Status       ModerationStatus `bson:"status" json:"status"`
+	AutoHidden   bool             `bson:"auto_hidden" json:"auto_hidden"`
+	Announce     bool             `bson:"announce,omitempty" json:"announce,omitempty"`
+	ResolvedBy   string           `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time       `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	CreatedAt    time.Time        `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time        `bson:"updated_at" json:"updated_at"`
+}
+
+// ModerationAction is the audit record of a moderator decision
+type ModerationAction struct {
+	ActionID    string    `bson:"action_id" json:"action_id"`
+	ItemID      string    `bson:"item_id" json:"item_id"`
+	TargetType  string    `bson:"target_type" json:"target_type"`
+	TargetID    string    `bson:"target_id" json:"target_id"`
+	AuthorID    string    `bson:"author_id" json:"author_id"`
+	ModeratorID string    `bson:"moderator_id" json:"moderator_id"`
+	Action      string    `bson:"action" json:"action"`
+	Note        string    `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason" validate:"required,oneof=spam abuse harassment spoiler other"`
+	Details string `json:"details" validate:"max=500"`
+}
+
+type ModerationActionRequest struct {
+	Action string `json:"action" validate:"required,oneof=approve hide delete ban"`
+	Note   string `json:"note" validate:"max=500"`
+}
@@ -18,17 +18,48 @@ type Ranking struct {
 
 type Review struct {
 	ReviewID  string    `bson:"review_id" json:"review_id"`
+	TMDBID    int       `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	ImdbID    string    `bson:"imdb_id,omitempty" json:"imdb_id,omitempty"`
 	UserID    string    `bson:"user_id" json:"user_id" validate:"required"`
 	UserName  string    `bson:"user_name" json:"user_name" validate:"required"`
 	Comment   string    `bson:"comment" json:"comment" validate:"required,min=10,max=1000"`
+	Edited    bool      `bson:"edited" json:"edited"`
+	Hidden    bool      `bson:"hidden" json:"hidden"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+
+	HelpfulCount   int `bson:"helpful_count" json:"helpful_count"`
+	UnhelpfulCount int `bson:"unhelpful_count" json:"unhelpful_count"`
+	HelpfulScore   int `bson:"helpful_score" json:"helpful_score"`
+
+	Spoiler       bool           `bson:"spoiler" json:"spoiler"`
+	SpoilerRanges []SpoilerRange `bson:"spoiler_ranges,omitempty" json:"spoiler_ranges,omitempty"`
+
+	EditHistory []ReviewEdit `bson:"edit_history,omitempty" json:"-"`
+}
+
+// ReviewEdit is a previous version of a review, kept when the review is edited
+type ReviewEdit struct {
+	Comment       string         `bson:"comment" json:"comment"`
+	SpoilerRanges []SpoilerRange `bson:"spoiler_ranges,omitempty" json:"spoiler_ranges,omitempty"`
+	EditedBy      string         `bson:"edited_by" json:"edited_by"`
+	EditedAt      time.Time      `bson:"edited_at" json:"edited_at"`
+}
+
+// SpoilerRange marks the characters [Start, End) of a text as a spoiler.
+// Offsets count code points in the stored text, which has the markup removed.
+type SpoilerRange struct {
+	Start int `bson:"start" json:"start"`
+	End   int `bson:"end" json:"end"`
 }
 
 type Rating struct {
+	TMDBID    int       `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	ImdbID    string    `bson:"imdb_id,omitempty" json:"imdb_id,omitempty"`
 	UserID    string    `bson:"user_id" json:"user_id" validate:"required"`
 	Rating    float64   `bson:"rating" json:"rating" validate:"required,min=0,max=10"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
 }
 
 type Movie struct {
@@ -38,15 +69,17 @@ type Movie struct {
 	PosterPath    string        `bson:"poster_path" json:"poster_path" validate:"required,url"`
 	YouTubeID     string        `bson:"youtube_id" json:"youtube_id" validate:"required"`
 	Genre         []Genre       `bson:"genre" json:"genre" validate:"required,dive"`
-	Reviews       []Review      `bson:"reviews" json:"reviews"`
-	Ratings       []Rating      `bson:"ratings" json:"ratings"`
+	Reviews       []Review      `bson:"-" json:"reviews"`
+	Ratings       []Rating      `bson:"-" json:"ratings"`
 	AverageRating float64       `bson:"average_rating" json:"average_rating"`
 	TotalRatings  int           `bson:"total_ratings" json:"total_ratings"`
+	RatingSum     float64       `bson:"rating_sum" json:"-"`
 	Ranking       Ranking       `bson:"ranking" json:"ranking" validate:"required"`
 }
 
 type AddReviewRequest struct {
 	Comment string `json:"comment" validate:"required,min=10,max=1000"`
+	Spoiler bool   `json:"spoiler"`
 }
 
 type AddRatingRequest struct {
@@ -55,4 +88,5 @@ type AddRatingRequest struct {
 
 type UpdateReviewRequest struct {
 	Comment string `json:"comment" validate:"required,min=10,max=1000"`
+	Spoiler *bool  `json:"spoiler"`
 }
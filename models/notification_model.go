@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationCommentReply   NotificationType = "comment_reply"
+	NotificationReviewVote     NotificationType = "review_vote"
+	NotificationCommentLike    NotificationType = "comment_like"
+	NotificationContentRemoved NotificationType = "content_removed"
+	NotificationContentHidden  NotificationType = "content_hidden"
+	NotificationAccountBanned  NotificationType = "account_banned"
+	// NotificationTitleAvailable tells the voters on a title request that it was added
+	NotificationTitleAvailable NotificationType = "title_available"
+)
+
+// NotificationTypes lists every type a user can switch off in their preferences
+var NotificationTypes = []NotificationType{
+	NotificationCommentReply,
+	NotificationReviewVote,
+	NotificationCommentLike,
+	NotificationContentRemoved,
+	NotificationContentHidden,
+	NotificationAccountBanned,
+	NotificationTitleAvailable,
+}
+
+// Notification is one entry in a user's inbox. Documents are removed by a TTL
+// index on expires_at.
+type Notification struct {
+	NotificationID string           `bson:"notification_id" json:"notification_id"`
+	UserID         string           `bson:"user_id" json:"user_id"`
+	Type           NotificationType `bson:"type" json:"type"`
+	ActorID        string           `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorName      string           `bson:"actor_name,omitempty" json:"actor_name,omitempty"`
+	TargetType     string           `bson:"target_type" json:"target_type"`
+	TargetID       string           `bson:"target_id" json:"target_id"`
+	TMDBID         int              `bson:"tmdb_id,omitempty" json:"tmdb_id,omitempty"`
+	ImdbID         string           `bson:"imdb_id,omitempty" json:"imdb_id,omitempty"`
+	Message        string           `bson:"message" json:"message"`
+	Read           bool             `bson:"read" json:"read"`
+	CreatedAt      time.Time        `bson:"created_at" json:"created_at"`
+	ReadAt         *time.Time       `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	ExpiresAt      time.Time        `bson:"expires_at" json:"expires_at"`
+}
+
+type MarkNotificationsReadRequest struct {
+	// NotificationIDs marks only these notifications; empty marks everything
+	NotificationIDs []string `json:"notification_ids" validate:"max=100"`
+}
+
+type NotificationPreferencesRequest struct {
+	Preferences map[NotificationType]bool `json:"preferences" validate:"required,dive,keys,oneof=comment_reply review_vote comment_like content_removed content_hidden account_banned title_available,endkeys"`
+}
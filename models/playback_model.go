@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+type PlaybackKind string
+
+const (
+	// PlaybackHLS is an adaptive stream from the server's HLS renditions
+	PlaybackHLS PlaybackKind = "hls"
+	// PlaybackFile is the single video file in media storage
+	PlaybackFile PlaybackKind = "file"
+	// PlaybackExternal is a video hosted elsewhere, which can't be signed
+	PlaybackExternal PlaybackKind = "external"
+)
+
+// PlaybackRequest picks an episode of a show; season and episode are left
+// out for movies. A device moving on to something else, such as the next
+// episode, sends its current session_id to keep its place in the stream limit.
+// The capability hints pick between a title's video sources.
+type PlaybackRequest struct {
+	Season     *int   `json:"season" validate:"required_with=Episode,omitempty,min=0"`
+	Episode    *int   `json:"episode" validate:"required_with=Season,omitempty,min=1"`
+	SessionID  string `json:"session_id" validate:"max=64"`
+	DeviceName string `json:"device_name" validate:"max=100"`
+	PlaybackCapabilities
+}
+
+// PlaybackResponse tells a player where to stream a title from. Signed URLs
+// stop working at ExpiresAt.
+type PlaybackResponse struct {
+	TMDBID    int          `json:"tmdb_id"`
+	Season    *int         `json:"season,omitempty"`
+	Episode   *int         `json:"episode,omitempty"`
+	Kind      PlaybackKind `json:"kind"`
+	URL       string       `json:"url"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+	SessionID string       `json:"session_id"`
+	// Source describes the video source picked, unless the stream is HLS
+	Source *VideoSource `json:"source,omitempty"`
+	// Subtitles carry their own signed URLs, for players not using HLS
+	Subtitles   []SubtitleTrack `json:"subtitles"`
+	AudioTracks []AudioTrack    `json:"audio_tracks"`
+	// Thumbnails is the signed URL of the WebVTT scrubbing preview track
+	Thumbnails string `json:"thumbnails,omitempty"`
+	// Markers and Chapters drive "skip intro" and "next episode" buttons
+	Markers  []Marker  `json:"markers,omitempty"`
+	Chapters []Chapter `json:"chapters,omitempty"`
+	// HeartbeatInterval is how often, in seconds, the player should send a heartbeat
+	HeartbeatInterval int `json:"heartbeat_interval"`
+}
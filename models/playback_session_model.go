@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// PlaybackSession is one device playing something on an account. Players
+// keep it alive with heartbeats; it ends at ExpiresAt once they stop.
+type PlaybackSession struct {
+	SessionID     string    `bson:"session_id" json:"session_id"`
+	UserID        string    `bson:"user_id" json:"user_id"`
+	TMDBID        int       `bson:"tmdb_id" json:"tmdb_id"`
+	Season        *int      `bson:"season,omitempty" json:"season,omitempty"`
+	Episode       *int      `bson:"episode,omitempty" json:"episode,omitempty"`
+	DeviceName    string    `bson:"device_name" json:"device_name"`
+	UserAgent     string    `bson:"user_agent" json:"user_agent"`
+	IPAddress     string    `bson:"ip_address" json:"ip_address"`
+	StartedAt     time.Time `bson:"started_at" json:"started_at"`
+	LastHeartbeat time.Time `bson:"last_heartbeat" json:"last_heartbeat"`
+	ExpiresAt     time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// PlaybackHeartbeatResponse is a session kept alive by a heartbeat, with a
+// freshly signed query the player should put on the stream, subtitle and
+// thumbnail URLs it fetches from then on
+type PlaybackHeartbeatResponse struct {
+	PlaybackSession
+	PlaybackQuery     string    `json:"playback_query"`
+	PlaybackExpiresAt time.Time `json:"playback_expires_at"`
+}
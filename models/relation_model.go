@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type RelationType string
+
+const (
+	// RelationBlock hides both users' content from each other and stops replies and votes between them
+	RelationBlock RelationType = "block"
+	// RelationMute hides the target's content from the user only
+	RelationMute RelationType = "mute"
+)
+
+// UserRelation is one entry in a user's block or mute list
+type UserRelation struct {
+	UserID    string       `bson:"user_id" json:"user_id"`
+	TargetID  string       `bson:"target_id" json:"target_id"`
+	Type      RelationType `bson:"type" json:"type"`
+	CreatedAt time.Time    `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// VideoSource is one encoding of a title's video, either hosted elsewhere at
+// URL or kept in media storage at StorageKey. Players are handed a source by
+// POST /media/:tmdb_id/playback, which picks one their capabilities suit.
+type VideoSource struct {
+	SourceID string `bson:"source_id" json:"source_id"`
+	// Label is the quality shown to viewers, such as "1080p" or "4K HDR"
+	Label  string `bson:"label,omitempty" json:"label,omitempty"`
+	Width  int    `bson:"width,omitempty" json:"width,omitempty"`
+	Height int    `bson:"height,omitempty" json:"height,omitempty"`
+	// Codec is the video codec: h264, hevc, vp9 or av1
+	Codec string `bson:"codec,omitempty" json:"codec,omitempty"`
+	// Bitrate is in kilobits per second
+	Bitrate    int    `bson:"bitrate,omitempty" json:"bitrate,omitempty"`
+	Language   string `bson:"language,omitempty" json:"language,omitempty"`
+	Container  string `bson:"container,omitempty" json:"container,omitempty"`
+	URL        string `bson:"url,omitempty" json:"-"`
+	StorageKey string `bson:"storage_key,omitempty" json:"-"`
+	Default    bool   `bson:"default" json:"default"`
+}
+
+type AddVideoSourceRequest struct {
+	Label      string `json:"label" validate:"max=50"`
+	Width      int    `json:"width" validate:"omitempty,min=1,max=16384"`
+	Height     int    `json:"height" validate:"omitempty,min=1,max=16384"`
+	Codec      string `json:"codec" validate:"omitempty,oneof=h264 hevc vp9 av1"`
+	Bitrate    int    `json:"bitrate" validate:"omitempty,min=1"`
+	Language   string `json:"language" validate:"omitempty,bcp47_language_tag"`
+	Container  string `json:"container" validate:"omitempty,oneof=mp4 m4v mov webm mkv ts hls"`
+	URL        string `json:"url" validate:"required_without=StorageKey,excluded_with=StorageKey,omitempty,url"`
+	StorageKey string `json:"storage_key" validate:"omitempty,max=1024"`
+	Default    bool   `json:"default"`
+}
+
+// PlaybackCapabilities are hints a player sends about what it can play.
+// Anything left out is taken to be no constraint.
+type PlaybackCapabilities struct {
+	// Codecs and Containers list what the player supports, e.g. ["h264",
+	// "hevc"] and ["mp4", "hls"]
+	Codecs     []string `json:"codecs" validate:"max=10,dive,max=20"`
+	Containers []string `json:"containers" validate:"max=10,dive,max=20"`
+	// MaxHeight and MaxBitrate cap the quality, in pixels and kilobits per
+	// second, e.g. for a small screen or a metered connection
+	MaxHeight  int    `json:"max_height" validate:"omitempty,min=1"`
+	MaxBitrate int    `json:"max_bitrate" validate:"omitempty,min=1"`
+	Language   string `json:"language" validate:"omitempty,bcp47_language_tag"`
+}
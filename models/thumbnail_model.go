@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ThumbnailTrack is the scrubbing preview track of a movie, or of one
+// episode of a show when Season and Episode are set: sprite sheets of frames
+// taken every Interval seconds, and a WebVTT file pointing each stretch of
+// the video at its tile. The files live under BaseDir in media storage.
+type ThumbnailTrack struct {
+	TrackID     string    `bson:"track_id" json:"track_id"`
+	TMDBID      int       `bson:"tmdb_id" json:"tmdb_id"`
+	Season      *int      `bson:"season,omitempty" json:"season,omitempty"`
+	Episode     *int      `bson:"episode,omitempty" json:"episode,omitempty"`
+	BaseDir     string    `bson:"base_dir" json:"-"`
+	Interval    float64   `bson:"interval" json:"interval"`
+	TileWidth   int       `bson:"tile_width" json:"tile_width"`
+	TileHeight  int       `bson:"tile_height" json:"tile_height"`
+	Columns     int       `bson:"columns" json:"columns"`
+	Rows        int       `bson:"rows" json:"rows"`
+	FrameCount  int       `bson:"frame_count" json:"frame_count"`
+	SpriteCount int       `bson:"sprite_count" json:"sprite_count"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+type TitleRequestStatus string
+
+const (
+	TitleRequestPending   TitleRequestStatus = "pending"
+	TitleRequestFulfilled TitleRequestStatus = "fulfilled"
+	TitleRequestRejected  TitleRequestStatus = "rejected"
+)
+
+// TitleRequest asks for a TMDB title to be added to the library. There is at
+// most one pending request per title; users asking again upvote it instead.
+// Title details are copied from TMDB when the request is made.
+type TitleRequest struct {
+	RequestID    string             `bson:"request_id" json:"request_id"`
+	TMDBID       int                `bson:"tmdb_id" json:"tmdb_id"`
+	MediaType    MediaType          `bson:"media_type" json:"media_type"`
+	Title        string             `bson:"title" json:"title"`
+	Overview     string             `bson:"overview" json:"overview"`
+	PosterPath   string             `bson:"poster_path" json:"poster_path"`
+	ReleaseDate  string             `bson:"release_date" json:"release_date"`
+	RequestedBy  string             `bson:"requested_by" json:"requested_by"`
+	VoteCount    int                `bson:"vote_count" json:"vote_count"`
+	Status       TitleRequestStatus `bson:"status" json:"status"`
+	ResolvedBy   string             `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	RejectReason string             `bson:"reject_reason,omitempty" json:"reject_reason,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+type CreateTitleRequestRequest struct {
+	TMDBID    int       `json:"tmdb_id" validate:"required"`
+	MediaType MediaType `json:"media_type" validate:"required,oneof=movie tv"`
+}
+
+type FulfilTitleRequestRequest struct {
+	VideoURL string `json:"video_url" validate:"required,url"`
+}
+
+type RejectTitleRequestRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
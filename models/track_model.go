@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+type SubtitleKind string
+
+const (
+	SubtitleKindSubtitles SubtitleKind = "subtitles"
+	// SubtitleKindCaptions also describe sounds, for viewers who can't hear them
+	SubtitleKindCaptions SubtitleKind = "captions"
+)
+
+// SubtitleTrack is a WebVTT subtitle file for a movie, or for one episode of
+// a show when Season and Episode are set. SRT uploads are converted to
+// WebVTT before they are stored.
+type SubtitleTrack struct {
+	TrackID    string       `bson:"track_id" json:"track_id"`
+	TMDBID     int          `bson:"tmdb_id" json:"tmdb_id"`
+	Season     *int         `bson:"season,omitempty" json:"season,omitempty"`
+	Episode    *int         `bson:"episode,omitempty" json:"episode,omitempty"`
+	Language   string       `bson:"language" json:"language"`
+	Label      string       `bson:"label" json:"label"`
+	Kind       SubtitleKind `bson:"kind" json:"kind"`
+	Forced     bool         `bson:"forced" json:"forced"`
+	Default    bool         `bson:"default" json:"default"`
+	Duration   float64      `bson:"duration" json:"duration"`
+	StorageKey string       `bson:"storage_key" json:"-"`
+	// URL is set in playback responses, signed like the stream itself
+	URL       string    `bson:"-" json:"url,omitempty"`
+	CreatedBy string    `bson:"created_by" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AudioTrack describes one of the audio tracks in a title's video, such as
+// a dub or an audio description track
+type AudioTrack struct {
+	TrackID   string    `bson:"track_id" json:"track_id"`
+	TMDBID    int       `bson:"tmdb_id" json:"tmdb_id"`
+	Season    *int      `bson:"season,omitempty" json:"season,omitempty"`
+	Episode   *int      `bson:"episode,omitempty" json:"episode,omitempty"`
+	Language  string    `bson:"language" json:"language"`
+	Label     string    `bson:"label" json:"label"`
+	Codec     string    `bson:"codec,omitempty" json:"codec,omitempty"`
+	Channels  int       `bson:"channels,omitempty" json:"channels,omitempty"`
+	Default   bool      `bson:"default" json:"default"`
+	CreatedBy string    `bson:"created_by" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// AddSubtitleTrackRequest is the form sent with a subtitle file upload
+type AddSubtitleTrackRequest struct {
+	Season   *int         `form:"season" validate:"required_with=Episode,omitempty,min=0"`
+	Episode  *int         `form:"episode" validate:"required_with=Season,omitempty,min=1"`
+	Language string       `form:"language" validate:"required,bcp47_language_tag"`
+	Label    string       `form:"label" validate:"required,max=100,excludesall=\"\r\n"`
+	Kind     SubtitleKind `form:"kind" validate:"omitempty,oneof=subtitles captions"`
+	Forced   bool         `form:"forced"`
+	Default  bool         `form:"default"`
+}
+
+type AddAudioTrackRequest struct {
+	Season   *int   `json:"season" validate:"required_with=Episode,omitempty,min=0"`
+	Episode  *int   `json:"episode" validate:"required_with=Season,omitempty,min=1"`
+	Language string `json:"language" validate:"required,bcp47_language_tag"`
+	Label    string `json:"label" validate:"required,max=100"`
+	Codec    string `json:"codec" validate:"max=50"`
+	Channels int    `json:"channels" validate:"omitempty,min=1,max=16"`
+	Default  bool   `json:"default"`
+}
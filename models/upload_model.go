@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+type UploadStatus string
+
+const (
+	UploadInProgress UploadStatus = "in_progress"
+	UploadCompleted  UploadStatus = "completed"
+)
+
+// Upload is a resumable upload of a video file for a Media entry. Offset is
+// how many bytes have been received; once it reaches Size the file is moved
+// into storage under StorageKey and becomes the media's video file.
+type Upload struct {
+	UploadID    string       `bson:"upload_id" json:"upload_id"`
+	TMDBID      int          `bson:"tmdb_id" json:"tmdb_id"`
+	Filename    string       `bson:"filename" json:"filename"`
+	Size        int64        `bson:"size" json:"size"`
+	Offset      int64        `bson:"offset" json:"offset"`
+	Status      UploadStatus `bson:"status" json:"status"`
+	StorageKey  string       `bson:"storage_key" json:"storage_key"`
+	CreatedBy   string       `bson:"created_by" json:"created_by"`
+	LockedUntil *time.Time   `bson:"locked_until,omitempty" json:"-"`
+	CreatedAt   time.Time    `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time    `bson:"updated_at" json:"updated_at"`
+	ExpiresAt   time.Time    `bson:"expires_at" json:"expires_at"`
+}
+
+type CreateUploadRequest struct {
+	TMDBID   int    `json:"tmdb_id" validate:"required"`
+	Filename string `json:"filename" validate:"required,max=255"`
+	Size     int64  `json:"size" validate:"required,gt=0"`
+}
@@ -13,12 +13,23 @@ type User struct {
 	LastName        string        `json:"last_name" bson:"last_name" validate:"required,min=2,max=100"`
 	Email           string        `json:"email" bson:"email" validate:"required,email"`
 	Password        string        `json:"password" bson:"password" validate:"required,min=6"`
-	Role            string        `json:"role" bson:"role" validate:"oneof=ADMIN USER"`
+	Role            string        `json:"role" bson:"role" validate:"oneof=ADMIN MODERATOR USER"`
 	CreatedAt       time.Time     `json:"created_at" bson:"created_at"`
 	UpdatedAt       time.Time     `json:"updated_at" bson:"updated_at"`
 	Token           string        `json:"token" bson:"token"`
 	RefreshToken    string        `json:"refresh_token" bson:"refresh_token"`
 	FavouriteGenres []Genre       `json:"favourite_genres" bson:"favourite_genres" validate:"required,dive"`
+	Banned          bool          `json:"banned" bson:"banned"`
+
+	Privacy PrivacySettings `json:"privacy" bson:"privacy"`
+
+	// NotificationPreferences switches notification types off; missing types are on
+	NotificationPreferences map[NotificationType]bool `json:"notification_preferences,omitempty" bson:"notification_preferences,omitempty"`
+}
+
+// SetRoleRequest changes a user's role
+type SetRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=ADMIN MODERATOR USER"`
 }
 
 type UserLogin struct {
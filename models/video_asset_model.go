@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// VideoAsset is the set of HLS renditions for a movie, or for one episode of
+// a show when Season and Episode are set. The files live under BaseDir in
+// media storage; the server writes the master playlist itself.
+type VideoAsset struct {
+	AssetID    string      `bson:"asset_id" json:"asset_id"`
+	TMDBID     int         `bson:"tmdb_id" json:"tmdb_id"`
+	Season     *int        `bson:"season,omitempty" json:"season,omitempty"`
+	Episode    *int        `bson:"episode,omitempty" json:"episode,omitempty"`
+	BaseDir    string      `bson:"base_dir" json:"-"`
+	Renditions []Rendition `bson:"renditions" json:"renditions"`
+	MasterURL  string      `bson:"-" json:"master_url"`
+	CreatedAt  time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+// Rendition is one pre-encoded quality level. Playlist is its media playlist
+// relative to the asset's BaseDir; segments sit next to it.
+type Rendition struct {
+	Name             string  `bson:"name" json:"name"`
+	Bandwidth        int     `bson:"bandwidth" json:"bandwidth"`
+	AverageBandwidth int     `bson:"average_bandwidth,omitempty" json:"average_bandwidth,omitempty"`
+	Resolution       string  `bson:"resolution,omitempty" json:"resolution,omitempty"`
+	Codecs           string  `bson:"codecs,omitempty" json:"codecs,omitempty"`
+	FrameRate        float64 `bson:"frame_rate,omitempty" json:"frame_rate,omitempty"`
+	Playlist         string  `bson:"playlist" json:"-"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Vote is one user's vote on a review or comment; Value is 1 or -1
+type Vote struct {
+	TargetType string    `bson:"target_type" json:"target_type"`
+	TargetID   string    `bson:"target_id" json:"target_id"`
+	UserID     string    `bson:"user_id" json:"user_id"`
+	Value      int       `bson:"value" json:"value"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type VoteRequest struct {
+	Value int `json:"value" validate:"required,oneof=1 -1"`
+}
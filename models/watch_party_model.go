@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WatchParty is a room for watching a title together. The live state lives
+// in memory while members are connected; the last known playback position and
+// host are saved here so a room can be picked up again.
+type WatchParty struct {
+	PartyID    string    `bson:"party_id" json:"party_id"`
+	InviteCode string    `bson:"invite_code" json:"invite_code"`
+	TMDBID     int       `bson:"tmdb_id" json:"tmdb_id"`
+	HostID     string    `bson:"host_id" json:"host_id"`
+	CreatedBy  string    `bson:"created_by" json:"created_by"`
+	Playing    bool      `bson:"playing" json:"playing"`
+	Position   float64   `bson:"position" json:"position"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+type CreateWatchPartyRequest struct {
+	TMDBID int `json:"tmdb_id" validate:"required"`
+}
+
+// WatchPartyTicket lets one WebSocket join a party as a user. Browsers can't
+// send a bearer token with a WebSocket, so the socket presents a ticket
+// instead; only its SHA-256 is stored, and it is deleted when used.
+type WatchPartyTicket struct {
+	TicketHash string    `bson:"ticket_hash"`
+	PartyID    string    `bson:"party_id"`
+	UserID     string    `bson:"user_id"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WatchlistEntry is one title on a user's private watchlist
+type WatchlistEntry struct {
+	UserID  string    `bson:"user_id" json:"user_id"`
+	TMDBID  int       `bson:"tmdb_id" json:"tmdb_id"`
+	AddedAt time.Time `bson:"added_at" json:"added_at"`
+	Media   *Media    `bson:"-" json:"media,omitempty"`
+}
+
+type AddWatchlistRequest struct {
+	TMDBID int `json:"tmdb_id" validate:"required"`
+}
@@ -9,22 +9,117 @@ import (
 func SetupProtectedRoutes(router *gin.Engine) {
 	protected := router.Group("/")
 	protected.Use(middleware.AuthMiddleware())
+	// Banned users can still watch and manage their account, but not post
+	notBanned := controller.RequireNotBanned()
 	{
-		protected.POST("/addmovie", controller.AddMovie())
-		protected.POST("/movie/:imdb_id/review", controller.AddReview())
-		protected.PUT("/movie/:imdb_id/review/:review_id", controller.UpdateReview())
+		protected.POST("/addmovie", notBanned, controller.AddMovie())
+		protected.POST("/movie/:imdb_id/review", notBanned, controller.AddReview())
+		protected.PUT("/movie/:imdb_id/review/:review_id", notBanned, controller.UpdateReview())
 		protected.DELETE("/movie/:imdb_id/review/:review_id", controller.DeleteReview())
-		protected.POST("/movie/:imdb_id/rating", controller.AddRating())
+		protected.POST("/movie/:imdb_id/review/:review_id/report", notBanned, controller.ReportReview())
+		protected.POST("/movie/:imdb_id/review/:review_id/vote", notBanned, controller.VoteReview())
+		protected.DELETE("/movie/:imdb_id/review/:review_id/vote", controller.ClearReviewVote())
+		protected.POST("/movie/:imdb_id/rating", notBanned, controller.AddRating())
+		protected.DELETE("/movie/:imdb_id/rating", controller.DeleteRating())
 
 		protected.GET("/search/movies", controller.SearchMovies())
 		protected.GET("/search/tv", controller.SearchTV())
 		protected.GET("/tmdb/movie/:tmdb_id", controller.GetMovieDetails())
 		protected.GET("/tmdb/tv/:tmdb_id", controller.GetTVDetails())
 
-		protected.POST("/media", controller.AddMedia())
-		protected.POST("/media/:tmdb_id/review", controller.AddMediaReview())
-		protected.POST("/media/:tmdb_id/comment", controller.AddMediaComment())
+		protected.POST("/media", notBanned, controller.AddMedia())
+		protected.POST("/media/:tmdb_id/review", notBanned, controller.AddMediaReview())
+		protected.PUT("/media/:tmdb_id/review/:review_id", notBanned, controller.UpdateMediaReview())
+		protected.DELETE("/media/:tmdb_id/review/:review_id", controller.DeleteMediaReview())
+		protected.GET("/media/:tmdb_id/review/:review_id/history", controller.GetMediaReviewHistory())
+		protected.POST("/media/:tmdb_id/review/:review_id/report", notBanned, controller.ReportMediaReview())
+		protected.POST("/media/:tmdb_id/review/:review_id/vote", notBanned, controller.VoteMediaReview())
+		protected.DELETE("/media/:tmdb_id/review/:review_id/vote", controller.ClearMediaReviewVote())
+		protected.POST("/media/:tmdb_id/comment", notBanned, controller.AddMediaComment())
+		protected.PATCH("/media/:tmdb_id/comment/:comment_id", notBanned, controller.UpdateMediaComment())
 		protected.DELETE("/media/:tmdb_id/comment/:comment_id", controller.DeleteMediaComment())
-		protected.POST("/media/:tmdb_id/rating", controller.AddMediaRating())
+		protected.POST("/media/:tmdb_id/comment/:comment_id/report", notBanned, controller.ReportMediaComment())
+		protected.POST("/media/:tmdb_id/comment/:comment_id/like", notBanned, controller.LikeMediaComment())
+		protected.DELETE("/media/:tmdb_id/comment/:comment_id/like", controller.UnlikeMediaComment())
+		protected.POST("/media/:tmdb_id/rating", notBanned, controller.AddMediaRating())
+		protected.DELETE("/media/:tmdb_id/rating", controller.DeleteMediaRating())
+
+		protected.POST("/media/:tmdb_id/playback", controller.CreatePlaybackURL())
+		protected.GET("/media/:tmdb_id/assets", controller.GetVideoAssets())
+		protected.GET("/me/playback_sessions", controller.GetPlaybackSessions())
+		protected.POST("/me/playback_sessions/:session_id/heartbeat", controller.PlaybackHeartbeat())
+		protected.DELETE("/me/playback_sessions/:session_id", controller.EndPlaybackSession())
+
+		protected.POST("/watch_parties", controller.CreateWatchParty())
+		protected.GET("/watch_parties/:invite_code", controller.GetWatchParty())
+		protected.POST("/watch_parties/:invite_code/ticket", controller.CreateWatchPartyTicket())
+
+		protected.GET("/me/notifications", controller.GetNotifications())
+		protected.GET("/me/notifications/unread_count", controller.GetUnreadNotificationCount())
+		protected.POST("/me/notifications/read", controller.MarkNotificationsRead())
+		protected.GET("/me/notification_preferences", controller.GetNotificationPreferences())
+		protected.PUT("/me/notification_preferences", controller.UpdateNotificationPreferences())
+
+		protected.GET("/me/feed", controller.GetFeed())
+		protected.GET("/me/privacy", controller.GetPrivacySettings())
+		protected.PUT("/me/privacy", controller.UpdatePrivacySettings())
+		protected.GET("/me/watchlist", controller.GetWatchlist())
+		protected.POST("/me/watchlist", controller.AddToWatchlist())
+		protected.DELETE("/me/watchlist/:tmdb_id", controller.RemoveFromWatchlist())
+		protected.POST("/users/:user_id/follow", notBanned, controller.FollowUser())
+		protected.DELETE("/users/:user_id/follow", controller.UnfollowUser())
+
+		protected.POST("/lists", notBanned, controller.CreateList())
+		protected.PATCH("/lists/:list_id", notBanned, controller.UpdateList())
+		protected.DELETE("/lists/:list_id", controller.DeleteList())
+		protected.POST("/lists/:list_id/items", notBanned, controller.AddListItem())
+		protected.PATCH("/lists/:list_id/items/:tmdb_id", notBanned, controller.UpdateListItem())
+		protected.DELETE("/lists/:list_id/items/:tmdb_id", controller.RemoveListItem())
+		protected.PUT("/lists/:list_id/order", notBanned, controller.ReorderList())
+		protected.POST("/lists/:list_id/like", notBanned, controller.LikeList())
+		protected.DELETE("/lists/:list_id/like", controller.UnlikeList())
+
+		protected.GET("/me/blocks", controller.GetBlockedUsers())
+		protected.GET("/me/mutes", controller.GetMutedUsers())
+		protected.POST("/users/:user_id/block", controller.BlockUser())
+		protected.DELETE("/users/:user_id/block", controller.UnblockUser())
+		protected.POST("/users/:user_id/mute", controller.MuteUser())
+		protected.DELETE("/users/:user_id/mute", controller.UnmuteUser())
+
+		protected.POST("/title_requests", notBanned, controller.CreateTitleRequest())
+		protected.POST("/title_requests/:request_id/vote", notBanned, controller.VoteTitleRequest())
+		protected.DELETE("/title_requests/:request_id/vote", controller.ClearTitleRequestVote())
+
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequireRole("ADMIN"))
+		{
+			admin.PUT("/users/:user_id/role", controller.SetUserRole())
+			admin.GET("/title_requests", controller.GetTitleRequestQueue())
+			admin.POST("/title_requests/:request_id/fulfil", controller.FulfilTitleRequest())
+			admin.POST("/title_requests/:request_id/reject", controller.RejectTitleRequest())
+			admin.OPTIONS("/uploads", controller.GetUploadOptions())
+			admin.POST("/uploads", controller.CreateUpload())
+			admin.GET("/uploads/:upload_id", controller.GetUpload())
+			admin.HEAD("/uploads/:upload_id", controller.GetUpload())
+			admin.PATCH("/uploads/:upload_id", controller.PatchUpload())
+			admin.DELETE("/uploads/:upload_id", controller.DeleteUpload())
+			admin.POST("/media/:tmdb_id/subtitles", controller.AddSubtitleTrack())
+			admin.DELETE("/subtitles/:track_id", controller.DeleteSubtitleTrack())
+			admin.POST("/media/:tmdb_id/audio_tracks", controller.AddAudioTrack())
+			admin.DELETE("/audio_tracks/:track_id", controller.DeleteAudioTrack())
+			admin.PUT("/media/:tmdb_id/markers", controller.SetMediaMarkers())
+			admin.POST("/media/:tmdb_id/sources", controller.AddVideoSource())
+			admin.DELETE("/media/:tmdb_id/sources/:source_id", controller.DeleteVideoSource())
+			admin.PUT("/media/:tmdb_id/episodes/:season/:episode/markers", controller.SetEpisodeMarkers())
+		}
+
+		moderation := protected.Group("/moderation")
+		moderation.Use(middleware.RequireRole("ADMIN", "MODERATOR"))
+		{
+			moderation.GET("/queue", controller.GetModerationQueue())
+			moderation.GET("/queue/:item_id", controller.GetModerationItem())
+			moderation.POST("/queue/:item_id/action", controller.ResolveModerationItem())
+			moderation.GET("/actions", controller.GetModerationActions())
+		}
 	}
 }
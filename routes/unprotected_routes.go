@@ -3,19 +3,49 @@ package routes
 import (
 	controller "github.com/Har2yQn78/Stream_Platform/controllers"
 	"github.com/Har2yQn78/Stream_Platform/database"
+	"github.com/Har2yQn78/Stream_Platform/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupUnProtectedRoutes(router *gin.Engine) {
 	router.GET("/movies", controller.GetMovies())
-	router.GET("/movie/:imdb_id", controller.GetMovieById())
-	router.GET("/movie/:imdb_id/reviews", controller.GetMovieReviews())
+	router.GET("/movie/:imdb_id", middleware.OptionalAuth(), controller.GetMovieById())
+	router.GET("/movie/:imdb_id/reviews", middleware.OptionalAuth(), controller.GetMovieReviews())
+	router.GET("/movie/:imdb_id/ratings", controller.GetMovieRatings())
 	// Auth routes
 	router.POST("/register", controller.RegisterUser())
 	router.POST("/login", controller.LoginUser(database.Client))
 
-	router.GET("/media", controller.GetAllMedia())
-	router.GET("/media/:tmdb_id", controller.GetMediaByTMDBID())
-	router.GET("/media/:tmdb_id/reviews", controller.GetMediaReviews())
-	router.GET("/media/:tmdb_id/comments", controller.GetMediaComments())
+	router.GET("/media", middleware.OptionalAuth(), controller.GetAllMedia())
+	router.GET("/media/:tmdb_id", middleware.OptionalAuth(), controller.GetMediaByTMDBID())
+	router.GET("/media/:tmdb_id/reviews", middleware.OptionalAuth(), controller.GetMediaReviews())
+	router.GET("/media/:tmdb_id/comments", middleware.OptionalAuth(), controller.GetMediaComments())
+	router.GET("/media/:tmdb_id/comment/:comment_id/replies", middleware.OptionalAuth(), controller.GetMediaCommentReplies())
+	router.GET("/media/:tmdb_id/ratings", controller.GetMediaRatings())
+	router.GET("/media/:tmdb_id/artwork/:kind", controller.GetMediaArtwork())
+	router.GET("/media/:tmdb_id/subtitles", controller.GetSubtitleTracks())
+	router.GET("/media/:tmdb_id/audio_tracks", controller.GetAudioTracks())
+	router.GET("/media/:tmdb_id/episodes/:season/:episode", controller.GetEpisode())
+	router.GET("/title_requests", controller.GetTitleRequests())
+	router.GET("/title_requests/:request_id", controller.GetTitleRequest())
+	router.GET("/lists", middleware.OptionalAuth(), controller.GetLists())
+	router.GET("/lists/:list_id", middleware.OptionalAuth(), controller.GetList())
+	router.GET("/users/:user_id", middleware.OptionalAuth(), controller.GetUserProfile())
+	router.GET("/users/:user_id/activity", middleware.OptionalAuth(), controller.GetUserActivity())
+	router.GET("/users/:user_id/lists", middleware.OptionalAuth(), controller.GetUserLists())
+	router.GET("/users/:user_id/followers", controller.GetFollowers())
+	router.GET("/users/:user_id/following", controller.GetFollowing())
+	router.GET("/media/:tmdb_id/events", middleware.OptionalAuth(), controller.GetMediaEvents())
+	// Players authenticate with the signed URL from POST /media/:tmdb_id/playback
+	router.GET("/media/:tmdb_id/stream", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.StreamMedia())
+	router.HEAD("/media/:tmdb_id/stream", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.StreamMedia())
+	router.GET("/media/:tmdb_id/sources/:source_id/stream", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.StreamMediaSource())
+	router.HEAD("/media/:tmdb_id/sources/:source_id/stream", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.StreamMediaSource())
+	router.GET("/media/:tmdb_id/hls/*path", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.ServeHLS())
+	router.GET("/media/:tmdb_id/episodes/:season/:episode/hls/*path", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.ServeHLS())
+	router.GET("/media/:tmdb_id/thumbnails/*path", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.ServeThumbnails())
+	router.GET("/media/:tmdb_id/episodes/:season/:episode/thumbnails/*path", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.ServeThumbnails())
+	router.GET("/media/:tmdb_id/subtitles/:track_id/vtt", middleware.PlaybackAuth(), controller.RequirePlaybackSession(), controller.ServeSubtitleTrack())
+	// The socket authenticates with a ticket from POST /watch_parties/:invite_code/ticket
+	router.GET("/watch_parties/:invite_code/ws", controller.JoinWatchParty())
 }
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ActivityService records what users do on titles and reads it back as feeds
+type ActivityService struct {
+	activities *mongo.Collection
+	users      *mongo.Collection
+}
+
+func NewActivityService(activities, users *mongo.Collection) *ActivityService {
+	return &ActivityService{activities: activities, users: users}
+}
+
+// Record stores an activity, replacing the actor's previous one of the same
+// type on the same title
+func (s *ActivityService) Record(ctx context.Context, activity models.Activity) error {
+	var actor models.User
+	err := s.users.FindOne(ctx, bson.M{"user_id": activity.ActorID}).Decode(&actor)
+	if err != nil {
+		return err
+	}
+
+	set := bson.M{
+		"actor_name": actor.FirstName + " " + actor.LastName,
+		"tmdb_id":    activity.TMDBID,
+		"imdb_id":    activity.ImdbID,
+		"review_id":  activity.ReviewID,
+		"excerpt":    activity.Excerpt,
+		"rating":     activity.Rating,
+		"created_at": time.Now(),
+	}
+	filter := bson.M{"actor_id": activity.ActorID, "type": activity.Type, "target_key": activity.TargetKey}
+	update := bson.M{"$set": set, "$setOnInsert": bson.M{"activity_id": bson.NewObjectID().Hex()}}
+
+	_, err = s.activities.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent record for the same title won the insert; update it instead
+		_, err = s.activities.UpdateOne(ctx, filter, bson.M{"$set": set})
+	}
+	return err
+}
+
+// Remove deletes the actor's activity of one type on a title
+func (s *ActivityService) Remove(ctx context.Context, actorID string, activityType models.ActivityType, targetKey string) error {
+	_, err := s.activities.DeleteOne(ctx, bson.M{"actor_id": actorID, "type": activityType, "target_key": targetKey})
+	return err
+}
+
+// RemoveReview deletes the activity for a review, whoever removed it
+func (s *ActivityService) RemoveReview(ctx context.Context, reviewID string) error {
+	_, err := s.activities.DeleteOne(ctx, bson.M{"type": models.ActivityReview, "review_id": reviewID})
+	return err
+}
+
+// Feed returns the newest activities of the given actors, leaving out the
+// kinds each actor keeps private, and the cursor for the next page. An empty
+// next cursor means there is nothing more.
+func (s *ActivityService) Feed(ctx context.Context, actorIDs []string, cursor string, limit int64) ([]models.Activity, string, error) {
+	activities := []models.Activity{}
+	if len(actorIDs) == 0 {
+		return activities, "", nil
+	}
+
+	visible, err := s.visibleTypes(ctx, actorIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(visible) == 0 {
+		return activities, "", nil
+	}
+	filters := bson.A{bson.M{"$or": visible}}
+
+	if cursor != "" {
+		createdAt, activityID, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filters = append(filters, bson.M{"$or": bson.A{
+			bson.M{"created_at": bson.M{"$lt": createdAt}},
+			bson.M{"created_at": createdAt, "activity_id": bson.M{"$lt": activityID}},
+		}})
+	}
+
+	// One extra document tells whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "activity_id", Value: -1}}).
+		SetLimit(limit + 1)
+	found, err := s.activities.Find(ctx, bson.M{"$and": filters}, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer found.Close(ctx)
+
+	if err = found.All(ctx, &activities); err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(activities)) <= limit {
+		return activities, "", nil
+	}
+	activities = activities[:limit]
+	last := activities[len(activities)-1]
+	return activities, encodeFeedCursor(last.CreatedAt, last.ActivityID), nil
+}
+
+// visibleTypes builds one clause per activity type naming the actors who
+// share that type
+func (s *ActivityService) visibleTypes(ctx context.Context, actorIDs []string) (bson.A, error) {
+	opts := options.Find().SetProjection(bson.M{"user_id": 1, "privacy": 1})
+	found, err := s.users.Find(ctx, bson.M{"user_id": bson.M{"$in": actorIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer found.Close(ctx)
+
+	var users []models.User
+	if err = found.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	clauses := bson.A{}
+	for _, activityType := range []models.ActivityType{models.ActivityReview, models.ActivityRating, models.ActivityWatchlist} {
+		var actors []string
+		for _, user := range users {
+			if !user.Privacy.Hides(activityType) {
+				actors = append(actors, user.UserID)
+			}
+		}
+		if len(actors) > 0 {
+			clauses = append(clauses, bson.M{"type": activityType, "actor_id": bson.M{"$in": actors}})
+		}
+	}
+	return clauses, nil
+}
+
+// Feed cursors are the position of the last activity on a page: its
+// creation time in milliseconds and its id
+func encodeFeedCursor(createdAt time.Time, activityID string) string {
+	raw := strconv.FormatInt(createdAt.UnixMilli(), 10) + ":" + activityID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	millisStr, activityID, found := strings.Cut(string(raw), ":")
+	millis, err := strconv.ParseInt(millisStr, 10, 64)
+	if !found || err != nil || activityID == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return time.UnixMilli(millis), activityID, nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// FilterVerdict is the outcome of screening a piece of user content
+type FilterVerdict string
+
+const (
+	VerdictAllow  FilterVerdict = "allow"
+	VerdictHold   FilterVerdict = "hold"
+	VerdictReject FilterVerdict = "reject"
+)
+
+// severity orders verdicts so the pipeline can keep the strictest one
+func (v FilterVerdict) severity() int {
+	switch v {
+	case VerdictReject:
+		return 2
+	case VerdictHold:
+		return 1
+	}
+	return 0
+}
+
+// FilterInput is the content handed to each filter. Filters may rewrite Text;
+// later filters see the rewritten version.
+type FilterInput struct {
+	UserID string
+	Text   string
+}
+
+// FilterResult is the combined outcome of a filter pipeline
+type FilterResult struct {
+	Verdict FilterVerdict `json:"verdict"`
+	Text    string        `json:"-"`
+	Reasons []string      `json:"reasons,omitempty"`
+}
+
+// ContentFilter is one step of the pipeline
+type ContentFilter interface {
+	Check(ctx context.Context, input *FilterInput) (FilterVerdict, string, error)
+}
+
+// ContentFilterPipeline runs filters in order and keeps the strictest verdict.
+// A reject stops the pipeline.
+type ContentFilterPipeline struct {
+	filters []ContentFilter
+}
+
+func NewContentFilterPipeline(filters ...ContentFilter) *ContentFilterPipeline {
+	return &ContentFilterPipeline{filters: filters}
+}
+
+// Run screens text written by userID
+func (p *ContentFilterPipeline) Run(ctx context.Context, userID, text string) (*FilterResult, error) {
+	input := &FilterInput{UserID: userID, Text: text}
+	result := &FilterResult{Verdict: VerdictAllow}
+
+	for _, filter := range p.filters {
+		verdict, reason, err := filter.Check(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if verdict == VerdictAllow {
+			continue
+		}
+
+		result.Reasons = append(result.Reasons, reason)
+		if verdict.severity() > result.Verdict.severity() {
+			result.Verdict = verdict
+		}
+		if verdict == VerdictReject {
+			break
+		}
+	}
+
+	result.Text = input.Text
+	if strings.TrimSpace(result.Text) == "" && result.Verdict != VerdictReject {
+		result.Verdict = VerdictReject
+		result.Reasons = append(result.Reasons, "content is empty")
+	}
+
+	return result, nil
+}
+
+// RecentContentSource looks up what a user has posted recently
+type RecentContentSource interface {
+	RecentContent(ctx context.Context, userID string, since time.Time) ([]string, error)
+}
+
+// NewDefaultContentFilter builds the standard pipeline from the environment:
+//
+//	CONTENT_FILTER_WORDS        comma separated blocked words
+//	CONTENT_FILTER_WORDS_FILE   file with one blocked word per line
+//	CONTENT_FILTER_WORD_ACTION  hold or reject (default reject)
+//	CONTENT_FILTER_MAX_URLS     links allowed before holding (default 2)
+func NewDefaultContentFilter(recent RecentContentSource) *ContentFilterPipeline {
+	var words []string
+	for _, word := range strings.Split(os.Getenv("CONTENT_FILTER_WORDS"), ",") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if path := os.Getenv("CONTENT_FILTER_WORDS_FILE"); path != "" {
+		if file, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(file)
+			for scanner.Scan() {
+				if word := strings.TrimSpace(scanner.Text()); word != "" && !strings.HasPrefix(word, "#") {
+					words = append(words, word)
+				}
+			}
+			file.Close()
+		}
+	}
+
+	wordAction := VerdictReject
+	if os.Getenv("CONTENT_FILTER_WORD_ACTION") == string(VerdictHold) {
+		wordAction = VerdictHold
+	}
+
+	maxURLs := 2
+	if value, err := strconv.Atoi(os.Getenv("CONTENT_FILTER_MAX_URLS")); err == nil && value >= 0 {
+		maxURLs = value
+	}
+
+	return NewContentFilterPipeline(
+		HTMLStripFilter{},
+		NewWordListFilter(words, wordAction),
+		LinkSpamFilter{MaxURLs: maxURLs},
+		RepetitionFilter{Recent: recent, Window: 10 * time.Minute},
+	)
+}
+
+// HTMLStripFilter removes markup, keeping only the text with its entities
+// decoded. Script and style contents are dropped. The result is plain text,
+// so whatever renders it must escape it like any other user input.
+type HTMLStripFilter struct{}
+
+func (HTMLStripFilter) Check(_ context.Context, input *FilterInput) (FilterVerdict, string, error) {
+	if !strings.ContainsAny(input.Text, "<&") {
+		return VerdictAllow, "", nil
+	}
+
+	var builder strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(input.Text))
+	skipping := ""
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// Stripping markup isn't a violation on its own
+			input.Text = strings.TrimSpace(builder.String())
+			return VerdictAllow, "", nil
+		case html.TextToken:
+			if skipping == "" {
+				builder.Write(tokenizer.Text())
+			}
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if tag := string(name); tag == "script" || tag == "style" {
+				skipping = tag
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if string(name) == skipping {
+				skipping = ""
+			}
+		}
+	}
+}
+
+// leetReplacer maps common character substitutions back to letters
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b",
+	"@", "a", "$", "s", "!", "i", "|", "l", "+", "t",
+)
+
+// normalizeWord lowercases, undoes leetspeak and drops anything that isn't a
+// letter, so "Sh1t" and "s.h.i.t" compare equal. Punctuation around the word
+// is trimmed first, so a closing "!" isn't read as an "i".
+func normalizeWord(word string) string {
+	word = strings.Trim(word, ".,!?;:\"'()")
+	word = leetReplacer.Replace(strings.ToLower(word))
+
+	var builder strings.Builder
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// collapseRepeats collapses runs of the same letter, so "shiiit" becomes "shit"
+func collapseRepeats(word string) string {
+	var builder strings.Builder
+	var last rune
+	for _, r := range word {
+		if r != last {
+			builder.WriteRune(r)
+		}
+		last = r
+	}
+	return builder.String()
+}
+
+// WordListFilter flags text containing blocked words, including spaced out,
+// character-substituted and stretched out spellings
+type WordListFilter struct {
+	words map[string]bool
+	// collapsed holds the words without doubled letters, which stretched
+	// spellings are matched against once their repeats are collapsed.
+	// Words like "ass" are left out, or "as" would match them.
+	collapsed map[string]bool
+	verdict   FilterVerdict
+}
+
+func NewWordListFilter(words []string, verdict FilterVerdict) *WordListFilter {
+	filter := &WordListFilter{words: map[string]bool{}, collapsed: map[string]bool{}, verdict: verdict}
+	for _, word := range words {
+		n := normalizeWord(word)
+		if n == "" {
+			continue
+		}
+		filter.words[n] = true
+		if collapseRepeats(n) == n {
+			filter.collapsed[n] = true
+		}
+	}
+	return filter
+}
+
+// matches reports whether a normalized word is on the list
+func (f *WordListFilter) matches(word string) bool {
+	return f.words[word] || f.collapsed[collapseRepeats(word)]
+}
+
+func (f *WordListFilter) Check(_ context.Context, input *FilterInput) (FilterVerdict, string, error) {
+	if len(f.words) == 0 {
+		return VerdictAllow, "", nil
+	}
+
+	// Match what readers see: spoiler markup is removed, and a stray marker
+	// splits words rather than reading as "ll"
+	text, _ := ParseSpoilers(input.Text)
+	text = strings.ReplaceAll(text, spoilerMarker, " ")
+
+	// Single letters in a row ("f u c k") are checked as one word once the
+	// run ends. Digits don't count, so "5" can't turn into an "s".
+	run := ""
+	runMatches := func() bool {
+		matched := len(run) > 1 && f.matches(run)
+		run = ""
+		return matched
+	}
+
+	for _, token := range strings.Fields(text) {
+		letters := []rune(strings.TrimFunc(token, func(r rune) bool { return !unicode.IsLetter(r) }))
+		if len(letters) == 1 {
+			run += normalizeWord(string(letters))
+			continue
+		}
+		if runMatches() || f.matches(normalizeWord(token)) {
+			return f.verdict, "blocked word", nil
+		}
+	}
+	if runMatches() {
+		return f.verdict, "blocked word", nil
+	}
+
+	return VerdictAllow, "", nil
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|io|co|ru|xyz|info|biz|top|link|click)\b`)
+
+// LinkSpamFilter holds content with more links than MaxURLs for review
+type LinkSpamFilter struct {
+	MaxURLs int
+}
+
+func (f LinkSpamFilter) Check(_ context.Context, input *FilterInput) (FilterVerdict, string, error) {
+	if count := len(urlPattern.FindAllString(input.Text, -1)); count > f.MaxURLs {
+		return VerdictHold, "too many links", nil
+	}
+	return VerdictAllow, "", nil
+}
+
+// maxRepeatedChars is the longest run of one character allowed before holding
+const maxRepeatedChars = 10
+
+// RepetitionFilter catches flooding: the same text posted again within Window,
+// long runs of one character, and text that is mostly one word repeated
+type RepetitionFilter struct {
+	Recent RecentContentSource
+	Window time.Duration
+}
+
+func (f RepetitionFilter) Check(ctx context.Context, input *FilterInput) (FilterVerdict, string, error) {
+	if f.Recent != nil && input.UserID != "" {
+		recent, err := f.Recent.RecentContent(ctx, input.UserID, time.Now().Add(-f.Window))
+		if err != nil {
+			return VerdictAllow, "", err
+		}
+		current := strings.ToLower(strings.Join(strings.Fields(input.Text), " "))
+		for _, text := range recent {
+			if strings.ToLower(strings.Join(strings.Fields(text), " ")) == current {
+				return VerdictReject, "duplicate of a recent post", nil
+			}
+		}
+	}
+
+	if hasRepeatedRun(input.Text, maxRepeatedChars) {
+		return VerdictHold, "repeated characters", nil
+	}
+
+	words := strings.Fields(strings.ToLower(input.Text))
+	if len(words) >= 8 {
+		counts := map[string]int{}
+		for _, word := range words {
+			counts[word]++
+			if counts[word]*2 > len(words) {
+				return VerdictHold, "repeated words", nil
+			}
+		}
+	}
+
+	return VerdictAllow, "", nil
+}
+
+// hasRepeatedRun reports whether text repeats one non-space character limit times in a row
+func hasRepeatedRun(text string, limit int) bool {
+	var last rune
+	run := 0
+	for _, r := range text {
+		if r == last && !unicode.IsSpace(r) {
+			run++
+			if run >= limit {
+				return true
+			}
+			continue
+		}
+		last, run = r, 1
+	}
+	return false
+}
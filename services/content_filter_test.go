@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHTMLStripFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "a fine film", "a fine film"},
+		{"tags", "<b>bold</b> and <i>italic</i>", "bold and italic"},
+		{"ampersand", "Tom & Jerry", "Tom & Jerry"},
+		{"encoded tag decoded", "&lt;img src=x onerror=alert(1)&gt;", "<img src=x onerror=alert(1)>"},
+		{"double encoded", "&amp;lt;b&amp;gt;", "&lt;b&gt;"},
+		{"script body", "hi<script>alert(1)</script> there", "hi there"},
+		{"style body", "<style>body{display:none}</style>visible", "visible"},
+		{"unclosed script", "before<script>alert(1)", "before"},
+		{"unclosed tag", "text <img src=x onerror=alert(1)", "text"},
+		{"split tag", "<<b>img src=x onerror=alert(1)>", "<img src=x onerror=alert(1)>"},
+		{"stray less than", "3 < 4", "3 < 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &FilterInput{Text: tt.in}
+			verdict, _, err := HTMLStripFilter{}.Check(context.Background(), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verdict != VerdictAllow {
+				t.Errorf("verdict = %s, want allow", verdict)
+			}
+			if input.Text != tt.want {
+				t.Errorf("text = %q, want %q", input.Text, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordListFilter(t *testing.T) {
+	filter := NewWordListFilter([]string{"darn", "ass"}, VerdictReject)
+
+	tests := []struct {
+		in   string
+		want FilterVerdict
+	}{
+		{"well darn it", VerdictReject},
+		{"D4RN!", VerdictReject},
+		{"d a r n", VerdictReject},
+		{"daaarn", VerdictReject},
+		{"as good as it gets", VerdictAllow},
+		{"the ||darn|| twist", VerdictReject},
+		{"||darn||", VerdictReject},
+		{"||da||rn", VerdictReject},
+		{"unclosed ||darn", VerdictReject},
+		{"a pass||ing|| remark", VerdictAllow},
+	}
+
+	for _, tt := range tests {
+		verdict, _, err := filter.Check(context.Background(), &FilterInput{Text: tt.in})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verdict != tt.want {
+			t.Errorf("Check(%q) = %s, want %s", tt.in, verdict, tt.want)
+		}
+	}
+}
+
+func TestContentFilterPipelineSpoilerMarkup(t *testing.T) {
+	pipeline := NewContentFilterPipeline(HTMLStripFilter{}, NewWordListFilter([]string{"darn"}, VerdictReject))
+
+	result, err := pipeline.Run(context.Background(), "", "it was <b>||darn||</b> good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictReject {
+		t.Errorf("verdict = %s, want reject", result.Verdict)
+	}
+}
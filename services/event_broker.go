@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Event is one message on a broker topic. IDs are opaque to clients, who send
+// the last one they saw back as Last-Event-ID to resume.
+type Event struct {
+	ID        string    `json:"id"`
+	Topic     string    `json:"-"`
+	Type      string    `json:"type"`
+	Data      any       `json:"data"`
+	ActorID   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventBroker is a topic based pub/sub. The in-process MemoryBroker serves a
+// single instance; a Mongo change stream implementation can replace it when
+// the API runs on several instances.
+type EventBroker interface {
+	Publish(ctx context.Context, topic, eventType, actorID string, data any) error
+	// Subscribe streams events published to topic after lastEventID. Missed
+	// reports that events since lastEventID are no longer available, so the
+	// client has to reload instead of resuming. The channel closes when ctx
+	// is done or the subscriber falls too far behind.
+	Subscribe(ctx context.Context, topic, lastEventID string) (events <-chan Event, missed bool, err error)
+}
+
+const subscriberBuffer = 64
+
+// MemoryBroker keeps the latest events of each topic in memory for replay
+type MemoryBroker struct {
+	mu          sync.Mutex
+	instance    string
+	sequence    uint64
+	history     int
+	topics      map[string][]Event
+	subscribers map[string]map[chan Event]struct{}
+}
+
+// NewMemoryBroker creates a broker that keeps up to history events per topic
+func NewMemoryBroker(history int) *MemoryBroker {
+	return &MemoryBroker{
+		// Event ids carry the instance so ids from before a restart are recognised
+		instance:    bson.NewObjectID().Hex(),
+		history:     history,
+		topics:      map[string][]Event{},
+		subscribers: map[string]map[chan Event]struct{}{},
+	}
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, topic, eventType, actorID string, data any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	event := Event{
+		ID:        b.instance + "-" + strconv.FormatUint(b.sequence, 10),
+		Topic:     topic,
+		Type:      eventType,
+		Data:      data,
+		ActorID:   actorID,
+		CreatedAt: time.Now(),
+	}
+
+	events := append(b.topics[topic], event)
+	if len(events) > b.history {
+		events = events[len(events)-b.history:]
+	}
+	b.topics[topic] = events
+
+	for subscriber := range b.subscribers[topic] {
+		select {
+		case subscriber <- event:
+		default:
+			// A subscriber that can't keep up is dropped; it reconnects and replays
+			b.removeLocked(topic, subscriber)
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic, lastEventID string) (<-chan Event, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay, missed := b.replayLocked(topic, lastEventID)
+
+	subscriber := make(chan Event, subscriberBuffer+len(replay))
+	for _, event := range replay {
+		subscriber <- event
+	}
+
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = map[chan Event]struct{}{}
+	}
+	b.subscribers[topic][subscriber] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		b.removeLocked(topic, subscriber)
+		b.mu.Unlock()
+	}()
+
+	return subscriber, missed, nil
+}
+
+// replayLocked returns the buffered events after lastEventID, and whether
+// some events after it have already been dropped from the buffer
+func (b *MemoryBroker) replayLocked(topic, lastEventID string) ([]Event, bool) {
+	if lastEventID == "" {
+		return nil, false
+	}
+
+	instance, sequenceStr, found := strings.Cut(lastEventID, "-")
+	sequence, err := strconv.ParseUint(sequenceStr, 10, 64)
+	if !found || err != nil || instance != b.instance {
+		return nil, true
+	}
+
+	events := b.topics[topic]
+	var replay []Event
+	for _, event := range events {
+		_, eventSequenceStr, _ := strings.Cut(event.ID, "-")
+		eventSequence, _ := strconv.ParseUint(eventSequenceStr, 10, 64)
+		if eventSequence > sequence {
+			replay = append(replay, event)
+		}
+	}
+
+	// The oldest buffered event must directly follow the client's last one,
+	// otherwise events were trimmed in between. Sequence numbers are shared
+	// across topics, so only a full buffer can have lost anything.
+	missed := len(events) == b.history && len(replay) == len(events)
+	return replay, missed
+}
+
+func (b *MemoryBroker) removeLocked(topic string, subscriber chan Event) {
+	if _, ok := b.subscribers[topic][subscriber]; !ok {
+		return
+	}
+	delete(b.subscribers[topic], subscriber)
+	if len(b.subscribers[topic]) == 0 {
+		delete(b.subscribers, topic)
+	}
+	close(subscriber)
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrNotFollowing = errors.New("you are not following this user")
+
+// FollowService manages who follows whom
+type FollowService struct {
+	follows *mongo.Collection
+}
+
+func NewFollowService(follows *mongo.Collection) *FollowService {
+	return &FollowService{follows: follows}
+}
+
+// Follow makes followerID follow followeeID. Following twice is a no-op.
+func (s *FollowService) Follow(ctx context.Context, followerID, followeeID string) error {
+	filter := bson.M{"follower_id": followerID, "followee_id": followeeID}
+	update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}}
+
+	_, err := s.follows.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// Unfollow stops followerID following followeeID
+func (s *FollowService) Unfollow(ctx context.Context, followerID, followeeID string) error {
+	result, err := s.follows.DeleteOne(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFollowing
+	}
+	return nil
+}
+
+// Separate removes the follows between two users in both directions, as
+// happens when one blocks the other
+func (s *FollowService) Separate(ctx context.Context, userID, otherID string) error {
+	_, err := s.follows.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"follower_id": userID, "followee_id": otherID},
+		bson.M{"follower_id": otherID, "followee_id": userID},
+	}})
+	return err
+}
+
+// IsFollowing reports whether followerID follows followeeID
+func (s *FollowService) IsFollowing(ctx context.Context, followerID, followeeID string) (bool, error) {
+	count, err := s.follows.CountDocuments(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FolloweeIDs returns everyone followerID follows
+func (s *FollowService) FolloweeIDs(ctx context.Context, followerID string) ([]string, error) {
+	var ids []string
+	err := s.follows.Distinct(ctx, "followee_id", bson.M{"follower_id": followerID}).Decode(&ids)
+	return ids, err
+}
+
+// Counts returns how many followers userID has and how many users they follow
+func (s *FollowService) Counts(ctx context.Context, userID string) (followers, following int64, err error) {
+	followers, err = s.follows.CountDocuments(ctx, bson.M{"followee_id": userID})
+	if err != nil {
+		return 0, 0, err
+	}
+	following, err = s.follows.CountDocuments(ctx, bson.M{"follower_id": userID})
+	return followers, following, err
+}
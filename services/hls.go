@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+)
+
+// HLSManifestFile is the manifest an ingest directory must contain
+const HLSManifestFile = "manifest.json"
+
+var (
+	renditionNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	resolutionPattern    = regexp.MustCompile(`^[0-9]+x[0-9]+$`)
+)
+
+// hlsManifest is the manifest of an ingest directory, e.g.
+//
+//	{"renditions": [{"name": "720p", "bandwidth": 2800000, "resolution": "1280x720",
+//	  "codecs": "avc1.64001f,mp4a.40.2", "playlist": "720p/index.m3u8"}]}
+type hlsManifest struct {
+	Renditions []struct {
+		Name             string  `json:"name"`
+		Bandwidth        int     `json:"bandwidth"`
+		AverageBandwidth int     `json:"average_bandwidth"`
+		Resolution       string  `json:"resolution"`
+		Codecs           string  `json:"codecs"`
+		FrameRate        float64 `json:"frame_rate"`
+		Playlist         string  `json:"playlist"`
+	} `json:"renditions"`
+}
+
+// LoadHLSManifest reads and checks the manifest in dir. Every rendition needs
+// a unique name, a bandwidth and a media playlist whose segments exist.
+func LoadHLSManifest(dir string) ([]models.Rendition, error) {
+	data, err := os.ReadFile(filepath.Join(dir, HLSManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest hlsManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", HLSManifestFile, err)
+	}
+	if len(manifest.Renditions) == 0 {
+		return nil, errors.New("the manifest lists no renditions")
+	}
+
+	var renditions []models.Rendition
+	seen := map[string]bool{}
+	for _, r := range manifest.Renditions {
+		if !renditionNamePattern.MatchString(r.Name) {
+			return nil, fmt.Errorf("rendition name %q must be 1-32 letters, digits, - or _", r.Name)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rendition %q is listed twice", r.Name)
+		}
+		seen[r.Name] = true
+		if r.Bandwidth <= 0 {
+			return nil, fmt.Errorf("rendition %q needs a bandwidth", r.Name)
+		}
+		if r.Resolution != "" && !resolutionPattern.MatchString(r.Resolution) {
+			return nil, fmt.Errorf("rendition %q: resolution must look like 1280x720", r.Name)
+		}
+		if strings.ContainsAny(r.Codecs, "\"\n") {
+			return nil, fmt.Errorf("rendition %q: codecs must not contain quotes", r.Name)
+		}
+		if !filepath.IsLocal(r.Playlist) || path.Ext(r.Playlist) != ".m3u8" {
+			return nil, fmt.Errorf("rendition %q needs a .m3u8 playlist inside the ingest directory", r.Name)
+		}
+		if err = checkMediaPlaylist(dir, r.Playlist); err != nil {
+			return nil, fmt.Errorf("rendition %q: %w", r.Name, err)
+		}
+
+		renditions = append(renditions, models.Rendition{
+			Name:             r.Name,
+			Bandwidth:        r.Bandwidth,
+			AverageBandwidth: r.AverageBandwidth,
+			Resolution:       r.Resolution,
+			Codecs:           r.Codecs,
+			FrameRate:        r.FrameRate,
+			Playlist:         path.Clean(r.Playlist),
+		})
+	}
+
+	return renditions, nil
+}
+
+// checkMediaPlaylist makes sure a playlist is an HLS media playlist and that
+// the segments and init sections it names are files next to it
+func checkMediaPlaylist(dir, playlist string) error {
+	file, err := os.Open(filepath.Join(dir, playlist))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	playlistDir := filepath.Join(dir, filepath.Dir(playlist))
+	scanner := bufio.NewScanner(file)
+	first, segments := true, 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if first {
+			if line != "#EXTM3U" {
+				return errors.New("the playlist must start with #EXTM3U")
+			}
+			first = false
+			continue
+		}
+
+		var uri string
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF"):
+			return errors.New("expected a media playlist, found a master playlist")
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			uri = playlistAttribute(line, "URI")
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		default:
+			uri = line
+			segments++
+		}
+
+		if !filepath.IsLocal(uri) {
+			return fmt.Errorf("%q must be a relative path inside the rendition directory", uri)
+		}
+		if _, err = os.Stat(filepath.Join(playlistDir, uri)); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+	if segments == 0 {
+		return errors.New("the playlist has no segments")
+	}
+	return nil
+}
+
+// playlistAttribute reads a quoted attribute such as URI="init.mp4" from a tag line
+func playlistAttribute(line, name string) string {
+	_, rest, found := strings.Cut(line, name+`="`)
+	if !found {
+		return ""
+	}
+	value, _, _ := strings.Cut(rest, `"`)
+	return value
+}
+
+// HLSMasterPath is the API path of the master playlist for a movie, or for
+// an episode when season and episode are set
+func HLSMasterPath(tmdbID int, season, episode *int) string {
+	if season != nil && episode != nil {
+		return fmt.Sprintf("/media/%d/episodes/%d/%d/hls/master.m3u8", tmdbID, *season, *episode)
+	}
+	return fmt.Sprintf("/media/%d/hls/master.m3u8", tmdbID)
+}
+
+// SubtitleGroup is the group ID subtitle tracks share in master playlists
+const SubtitleGroup = "subs"
+
+// SubtitlePlaylistURI is where the master playlist points players for a
+// subtitle track. It has no slash, so it can't clash with a rendition's files.
+func SubtitlePlaylistURI(trackID string) string {
+	return "subtitles-" + trackID + ".m3u8"
+}
+
+// SubtitleFileURI is where a subtitle track's playlist points to its WebVTT file
+func SubtitleFileURI(trackID string) string {
+	return "subtitles-" + trackID + ".vtt"
+}
+
+// MasterPlaylist writes the HLS master playlist for a set of renditions,
+// lowest bandwidth first, offering the subtitle tracks as alternatives. Each
+// variant is at "<name>/<playlist file>", relative to the master playlist's
+// URL, with query appended to every URI when set.
+func MasterPlaylist(renditions []models.Rendition, subtitles []models.SubtitleTrack, query string) string {
+	sorted := slices.Clone(renditions)
+	slices.SortFunc(sorted, func(a, b models.Rendition) int {
+		return a.Bandwidth - b.Bandwidth
+	})
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	for _, track := range subtitles {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"%s\",NAME=\"%s\",LANGUAGE=\"%s\",DEFAULT=%s,AUTOSELECT=YES,FORCED=%s,URI=\"%s\"\n",
+			SubtitleGroup, track.Label, track.Language, playlistBool(track.Default), playlistBool(track.Forced), withQuery(SubtitlePlaylistURI(track.TrackID), query))
+	}
+	for _, r := range sorted {
+		attributes := []string{"BANDWIDTH=" + strconv.Itoa(r.Bandwidth)}
+		if r.AverageBandwidth > 0 {
+			attributes = append(attributes, "AVERAGE-BANDWIDTH="+strconv.Itoa(r.AverageBandwidth))
+		}
+		if r.Resolution != "" {
+			attributes = append(attributes, "RESOLUTION="+r.Resolution)
+		}
+		if r.Codecs != "" {
+			attributes = append(attributes, `CODECS="`+r.Codecs+`"`)
+		}
+		if r.FrameRate > 0 {
+			attributes = append(attributes, "FRAME-RATE="+strconv.FormatFloat(r.FrameRate, 'f', 3, 64))
+		}
+		if len(subtitles) > 0 {
+			attributes = append(attributes, `SUBTITLES="`+SubtitleGroup+`"`)
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:%s\n%s\n", strings.Join(attributes, ","), withQuery(r.Name+"/"+path.Base(r.Playlist), query))
+	}
+	return b.String()
+}
+
+func playlistBool(value bool) string {
+	if value {
+		return "YES"
+	}
+	return "NO"
+}
+
+func withQuery(uri, query string) string {
+	if query == "" {
+		return uri
+	}
+	if strings.Contains(uri, "?") {
+		return uri + "&" + query
+	}
+	return uri + "?" + query
+}
+
+// AppendPlaylistQuery adds query to every URI in a media playlist: the
+// segment lines and the URI attributes of tags such as EXT-X-MAP. Relative
+// URIs resolve without the playlist's own query, so this is how a signed
+// query reaches the segments.
+func AppendPlaylistQuery(playlist []byte, query string) []byte {
+	if query == "" {
+		return playlist
+	}
+
+	lines := strings.Split(string(playlist), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			if uri := playlistAttribute(trimmed, "URI"); uri != "" {
+				lines[i] = strings.Replace(line, `URI="`+uri+`"`, `URI="`+withQuery(uri, query)+`"`, 1)
+			}
+		default:
+			lines[i] = withQuery(trimmed, query)
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+)
+
+// ErrRuntimeUnknown means a video's markers can't be checked because its
+// runtime isn't known
+var ErrRuntimeUnknown = errors.New("the runtime is unknown, so markers can't be checked against it")
+
+// markerRuntimeSlack allows for TMDB rounding runtimes to whole minutes
+const markerRuntimeSlack = time.Minute
+
+// CheckMarkers checks markers and chapters against a video's runtime and
+// sorts both by start time. Each kind of marker may appear once, markers
+// can't overlap, and no two chapters can start at the same time.
+func CheckMarkers(markers []models.Marker, chapters []models.Chapter, runtime time.Duration) error {
+	if len(markers) == 0 && len(chapters) == 0 {
+		return nil
+	}
+	if runtime <= 0 {
+		return ErrRuntimeUnknown
+	}
+	limit := (runtime + markerRuntimeSlack).Seconds()
+
+	slices.SortFunc(markers, func(a, b models.Marker) int {
+		return compareSeconds(a.Start, b.Start)
+	})
+	seen := map[models.MarkerType]bool{}
+	for i, marker := range markers {
+		if seen[marker.Type] {
+			return fmt.Errorf("there can only be one %s marker", marker.Type)
+		}
+		seen[marker.Type] = true
+		if marker.End > limit {
+			return fmt.Errorf("the %s marker ends after the video does", marker.Type)
+		}
+		if i > 0 && marker.Start < markers[i-1].End {
+			return fmt.Errorf("the %s and %s markers overlap", markers[i-1].Type, marker.Type)
+		}
+	}
+
+	slices.SortFunc(chapters, func(a, b models.Chapter) int {
+		return compareSeconds(a.Start, b.Start)
+	})
+	for i, chapter := range chapters {
+		if chapter.Start >= limit {
+			return fmt.Errorf("chapter %q starts after the video ends", chapter.Title)
+		}
+		if i > 0 && chapter.Start == chapters[i-1].Start {
+			return fmt.Errorf("chapters %q and %q start at the same time", chapters[i-1].Title, chapter.Title)
+		}
+	}
+	return nil
+}
+
+func compareSeconds(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
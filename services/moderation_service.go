@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrAlreadyReported         = errors.New("you have already reported this content")
+	ErrModerationItemNotFound  = errors.New("moderation item not found")
+	ErrUnknownModerationTarget = errors.New("unknown moderation target type")
+	ErrUnknownModerationAction = errors.New("unknown moderation action")
+)
+
+const defaultHideThreshold = 3
+
+// ModerationTarget describes a kind of reportable content
+type ModerationTarget struct {
+	Collection *mongo.Collection
+	IDField    string
+	// Remove deletes the content; comments use it to leave a tombstone behind
+	Remove func(ctx context.Context, targetID string) error
+	// Announce, if set, sends the notifications, live events and feed entries
+	// that were held back with the content, once a moderator approves it
+	Announce func(ctx context.Context, targetID string) error
+}
+
+// ModerationService collects reports into a queue of moderation items, hides
+// content that crosses the report threshold and records moderator decisions
+type ModerationService struct {
+	reports       *mongo.Collection
+	items         *mongo.Collection
+	actions       *mongo.Collection
+	users         *mongo.Collection
+	targets       map[string]ModerationTarget
+	hideThreshold int
+}
+
+// NewModerationService creates a moderation service. The auto-hide threshold
+// is read from MODERATION_HIDE_THRESHOLD.
+func NewModerationService(reports, items, actions, users *mongo.Collection, targets map[string]ModerationTarget) *ModerationService {
+	threshold := defaultHideThreshold
+	if value, err := strconv.Atoi(os.Getenv("MODERATION_HIDE_THRESHOLD")); err == nil && value > 0 {
+		threshold = value
+	}
+
+	return &ModerationService{
+		reports:       reports,
+		items:         items,
+		actions:       actions,
+		users:         users,
+		targets:       targets,
+		hideThreshold: threshold,
+	}
+}
+
+// Report files a user's report against a piece of content, hiding the content
+// once enough reports have come in
+func (s *ModerationService) Report(ctx context.Context, targetType, targetID, authorID, content, reporterID, reason, details string) (*models.ModerationItem, error) {
+	target, ok := s.targets[targetType]
+	if !ok {
+		return nil, ErrUnknownModerationTarget
+	}
+
+	count, err := s.reports.CountDocuments(ctx, bson.M{"target_type": targetType, "target_id": targetID, "reporter_id": reporterID})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyReported
+	}
+
+	item, err := s.openItem(ctx, targetType, targetID, authorID, content, reason, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.Report{
+		ReportID:   bson.NewObjectID().Hex(),
+		ItemID:     item.ItemID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		Details:    details,
+		CreatedAt:  time.Now(),
+	}
+	if _, err = s.reports.InsertOne(ctx, report); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyReported
+		}
+		return nil, err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"report_count": 1, "total_reports": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err = s.items.FindOneAndUpdate(ctx, bson.M{"item_id": item.ItemID}, update, opts).Decode(item); err != nil {
+		return nil, err
+	}
+
+	if item.ReportCount >= s.hideThreshold && !item.AutoHidden {
+		if err = s.setHidden(ctx, target, targetID, true); err != nil {
+			return nil, err
+		}
+		_, err = s.items.UpdateOne(ctx, bson.M{"item_id": item.ItemID}, bson.M{"$set": bson.M{"auto_hidden": true}})
+		if err != nil {
+			return nil, err
+		}
+		item.AutoHidden = true
+	}
+
+	return item, nil
+}
+
+// Hold puts content in the queue without a user report, hidden until a
+// moderator approves it. Used by automated filters. With announce set the
+// target's Announce runs on approval, for content nobody was told about yet.
+func (s *ModerationService) Hold(ctx context.Context, targetType, targetID, authorID, content, reason string, announce bool) (*models.ModerationItem, error) {
+	target, ok := s.targets[targetType]
+	if !ok {
+		return nil, ErrUnknownModerationTarget
+	}
+
+	if err := s.setHidden(ctx, target, targetID, true); err != nil {
+		return nil, err
+	}
+
+	item, err := s.openItem(ctx, targetType, targetID, authorID, content, reason, true)
+	if err != nil || !announce || item.Announce {
+		return item, err
+	}
+
+	// Set separately so a later hold of an edit can't clear it
+	_, err = s.items.UpdateOne(ctx, bson.M{"item_id": item.ItemID}, bson.M{"$set": bson.M{"announce": true}})
+	if err != nil {
+		return nil, err
+	}
+	item.Announce = true
+	return item, nil
+}
+
+// openItem finds or creates the open queue entry for a piece of content
+func (s *ModerationService) openItem(ctx context.Context, targetType, targetID, authorID, content, reason string, autoHidden bool) (*models.ModerationItem, error) {
+	now := time.Now()
+	set := bson.M{"status": models.ModerationStatusOpen, "content": content, "updated_at": now}
+	if autoHidden {
+		set["auto_hidden"] = true
+	}
+
+	update := bson.M{
+		"$set":      set,
+		"$addToSet": bson.M{"reasons": reason},
+		"$setOnInsert": bson.M{
+			"item_id":       bson.NewObjectID().Hex(),
+			"target_type":   targetType,
+			"target_id":     targetID,
+			"author_id":     authorID,
+			"report_count":  0,
+			"total_reports": 0,
+			"created_at":    now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var item models.ModerationItem
+	filter := bson.M{"target_type": targetType, "target_id": targetID}
+	err := s.items.FindOneAndUpdate(ctx, filter, update, opts).Decode(&item)
+	if mongo.IsDuplicateKeyError(err) {
+		err = s.items.FindOneAndUpdate(ctx, filter, update, opts).Decode(&item)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// CloseTarget resolves the open queue entry for content its author or a
+// moderator deleted outside the queue, so it isn't approved or announced
+// later. Content without an open entry is left alone.
+func (s *ModerationService) CloseTarget(ctx context.Context, targetType, targetID, userID string) error {
+	now := time.Now()
+	filter := bson.M{"target_type": targetType, "target_id": targetID, "status": models.ModerationStatusOpen}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.ModerationStatusDeleted,
+			"report_count": 0,
+			"auto_hidden":  false,
+			"resolved_by":  userID,
+			"resolved_at":  now,
+			"updated_at":   now,
+		},
+		"$unset": bson.M{"announce": ""},
+	}
+	_, err := s.items.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Get returns a queue entry with its reports and audit trail
+func (s *ModerationService) Get(ctx context.Context, itemID string) (*models.ModerationItem, []models.Report, []models.ModerationAction, error) {
+	var item models.ModerationItem
+	err := s.items.FindOne(ctx, bson.M{"item_id": itemID}).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, nil, ErrModerationItemNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	sort := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	reports := []models.Report{}
+	cursor, err := s.reports.Find(ctx, bson.M{"item_id": itemID}, sort)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = cursor.All(ctx, &reports); err != nil {
+		return nil, nil, nil, err
+	}
+
+	actions := []models.ModerationAction{}
+	cursor, err = s.actions.Find(ctx, bson.M{"item_id": itemID}, sort)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = cursor.All(ctx, &actions); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &item, reports, actions, nil
+}
+
+// Resolve applies a moderator decision to a queue entry and records it in the audit trail
+func (s *ModerationService) Resolve(ctx context.Context, itemID, moderatorID, action, note string) (*models.ModerationItem, error) {
+	var item models.ModerationItem
+	err := s.items.FindOne(ctx, bson.M{"item_id": itemID}).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrModerationItemNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := s.targets[item.TargetType]
+	if !ok {
+		return nil, ErrUnknownModerationTarget
+	}
+
+	var status models.ModerationStatus
+	announce := false
+	switch action {
+	case "approve":
+		status = models.ModerationStatusApproved
+		err = s.setHidden(ctx, target, item.TargetID, false)
+		announce = item.Announce && target.Announce != nil
+	case "hide":
+		status = models.ModerationStatusHidden
+		err = s.setHidden(ctx, target, item.TargetID, true)
+	case "delete":
+		status = models.ModerationStatusDeleted
+		err = target.Remove(ctx, item.TargetID)
+	case "ban":
+		status = models.ModerationStatusBanned
+		if err = target.Remove(ctx, item.TargetID); err == nil {
+			err = s.ban(ctx, item.AuthorID, moderatorID)
+		}
+	default:
+		return nil, ErrUnknownModerationAction
+	}
+	if err != nil {
+		return nil, err
+	}
+	if announce {
+		if err = target.Announce(ctx, item.TargetID); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	set := bson.M{
+		"status":       status,
+		"report_count": 0,
+		"auto_hidden":  status == models.ModerationStatusHidden,
+		"resolved_by":  moderatorID,
+		"resolved_at":  now,
+		"updated_at":   now,
+	}
+	if announce {
+		set["announce"] = false
+	}
+	update := bson.M{"$set": set}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err = s.items.FindOneAndUpdate(ctx, bson.M{"item_id": itemID}, update, opts).Decode(&item); err != nil {
+		return nil, err
+	}
+
+	record := models.ModerationAction{
+		ActionID:    bson.NewObjectID().Hex(),
+		ItemID:      item.ItemID,
+		TargetType:  item.TargetType,
+		TargetID:    item.TargetID,
+		AuthorID:    item.AuthorID,
+		ModeratorID: moderatorID,
+		Action:      action,
+		Note:        note,
+		CreatedAt:   now,
+	}
+	if _, err = s.actions.InsertOne(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func (s *ModerationService) setHidden(ctx context.Context, target ModerationTarget, targetID string, hidden bool) error {
+	_, err := target.Collection.UpdateOne(ctx, bson.M{target.IDField: targetID}, bson.M{"$set": bson.M{"hidden": hidden}})
+	return err
+}
+
+// IsBanned reports whether a user has been banned from posting
+func (s *ModerationService) IsBanned(ctx context.Context, userID string) (bool, error) {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"banned": 1})
+	err := s.users.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return user.Banned, err
+}
+
+func (s *ModerationService) ban(ctx context.Context, userID, moderatorID string) error {
+	update := bson.M{"$set": bson.M{
+		"banned":     true,
+		"banned_by":  moderatorID,
+		"banned_at":  time.Now(),
+		"updated_at": time.Now(),
+	}}
+	_, err := s.users.UpdateOne(ctx, bson.M{"user_id": userID}, update)
+	return err
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultNotificationTTLDays = 30
+
+// notificationMessages are the inbox texts per type; %s is the actor's name
+var notificationMessages = map[models.NotificationType]string{
+	models.NotificationCommentReply:   "%s replied to your comment",
+	models.NotificationReviewVote:     "%s voted on your review",
+	models.NotificationCommentLike:    "%s liked your comment",
+	models.NotificationContentRemoved: "A moderator removed your post",
+	models.NotificationContentHidden:  "Your post was hidden by a moderator",
+	models.NotificationAccountBanned:  "Your account has been banned from posting",
+	models.NotificationTitleAvailable: "A title you requested is now available",
+}
+
+// NotificationEvent is something that happened to content. Notify fans it out
+// to one notification per recipient.
+type NotificationEvent struct {
+	Type       models.NotificationType
+	ActorID    string
+	Recipients []string
+	TargetType string
+	TargetID   string
+	TMDBID     int
+	ImdbID     string
+}
+
+// NotificationService turns events into notifications and manages users' inboxes
+type NotificationService struct {
+	notifications *mongo.Collection
+	users         *mongo.Collection
+	ttl           time.Duration
+}
+
+// NewNotificationService creates a notification service. Notifications
+// expire after NOTIFICATION_TTL_DAYS days.
+func NewNotificationService(notifications, users *mongo.Collection) *NotificationService {
+	days := defaultNotificationTTLDays
+	if value, err := strconv.Atoi(os.Getenv("NOTIFICATION_TTL_DAYS")); err == nil && value > 0 {
+		days = value
+	}
+
+	return &NotificationService{
+		notifications: notifications,
+		users:         users,
+		ttl:           time.Duration(days) * 24 * time.Hour,
+	}
+}
+
+// Notify delivers an event to each recipient who hasn't switched its type
+// off. The actor is never notified about their own action, and repeating an
+// event (a vote toggled back and forth) refreshes the existing notification
+// instead of adding another.
+func (s *NotificationService) Notify(ctx context.Context, event NotificationEvent) error {
+	recipients := make([]string, 0, len(event.Recipients))
+	seen := map[string]bool{}
+	for _, recipient := range event.Recipients {
+		if recipient == "" || recipient == event.ActorID || seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+		recipients = append(recipients, recipient)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	// Only users who haven't disabled this type get it
+	filter := bson.M{
+		"user_id": bson.M{"$in": recipients},
+		"notification_preferences." + string(event.Type): bson.M{"$ne": false},
+	}
+	cursor, err := s.users.Find(ctx, filter, options.Find().SetProjection(bson.M{"user_id": 1}))
+	if err != nil {
+		return err
+	}
+	var users []models.User
+	if err = cursor.All(ctx, &users); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	actorName := ""
+	if event.ActorID != "" {
+		var actor models.User
+		err = s.users.FindOne(ctx, bson.M{"user_id": event.ActorID}).Decode(&actor)
+		if err == nil {
+			actorName = actor.FirstName + " " + actor.LastName
+		} else if err != mongo.ErrNoDocuments {
+			return err
+		}
+	}
+
+	message := notificationMessages[event.Type]
+	if strings.Contains(message, "%s") {
+		name := actorName
+		if name == "" {
+			name = "Someone"
+		}
+		message = fmt.Sprintf(message, name)
+	}
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, 0, len(users))
+	for _, user := range users {
+		key := bson.M{
+			"user_id":     user.UserID,
+			"type":        event.Type,
+			"target_type": event.TargetType,
+			"target_id":   event.TargetID,
+			"actor_id":    event.ActorID,
+		}
+		update := bson.M{
+			"$set": bson.M{
+				"actor_name": actorName,
+				"tmdb_id":    event.TMDBID,
+				"imdb_id":    event.ImdbID,
+				"message":    message,
+				"read":       false,
+				"created_at": now,
+				"expires_at": now.Add(s.ttl),
+			},
+			"$unset":       bson.M{"read_at": ""},
+			"$setOnInsert": bson.M{"notification_id": bson.NewObjectID().Hex()},
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(key).SetUpdate(update).SetUpsert(true))
+	}
+
+	_, err = s.notifications.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// UnreadCount returns how many unread notifications a user has
+func (s *NotificationService) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	return s.notifications.CountDocuments(ctx, bson.M{"user_id": userID, "read": false})
+}
+
+// MarkRead marks the given notifications as read, or all of them when ids is empty
+func (s *NotificationService) MarkRead(ctx context.Context, userID string, ids []string) (int64, error) {
+	filter := bson.M{"user_id": userID, "read": false}
+	if len(ids) > 0 {
+		filter["notification_id"] = bson.M{"$in": ids}
+	}
+
+	result, err := s.notifications.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// Preferences returns a user's setting for every notification type
+func (s *NotificationService) Preferences(ctx context.Context, userID string) (map[models.NotificationType]bool, error) {
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"notification_preferences": 1})
+	if err := s.users.FindOne(ctx, bson.M{"user_id": userID}, opts).Decode(&user); err != nil {
+		return nil, err
+	}
+
+	preferences := map[models.NotificationType]bool{}
+	for _, notificationType := range models.NotificationTypes {
+		enabled, ok := user.NotificationPreferences[notificationType]
+		preferences[notificationType] = !ok || enabled
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences switches the given notification types on or off
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID string, changes map[models.NotificationType]bool) (map[models.NotificationType]bool, error) {
+	set := bson.M{"updated_at": time.Now()}
+	for notificationType, enabled := range changes {
+		set["notification_preferences."+string(notificationType)] = enabled
+	}
+
+	if _, err := s.users.UpdateOne(ctx, bson.M{"user_id": userID}, bson.M{"$set": set}); err != nil {
+		return nil, err
+	}
+	return s.Preferences(ctx, userID)
+}
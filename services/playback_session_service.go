@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultMaxStreams = 3
+	// PlaybackHeartbeatInterval is how often players are asked to send a heartbeat
+	PlaybackHeartbeatInterval = 30 * time.Second
+	// A session ends when three heartbeats in a row are missed
+	playbackSessionTimeout = 3 * PlaybackHeartbeatInterval
+)
+
+var (
+	ErrStreamLimitReached      = errors.New("this account is already streaming on the maximum number of devices")
+	ErrPlaybackSessionNotFound = errors.New("playback session not found or already ended")
+)
+
+// PlaybackSessionService tracks who is streaming on which device and caps
+// how many streams an account can run at once
+type PlaybackSessionService struct {
+	sessions   *mongo.Collection
+	maxStreams int
+}
+
+// NewPlaybackSessionService creates a playback session service. The stream
+// limit is read from MAX_CONCURRENT_STREAMS.
+func NewPlaybackSessionService(sessions *mongo.Collection) *PlaybackSessionService {
+	maxStreams := defaultMaxStreams
+	if value, err := strconv.Atoi(os.Getenv("MAX_CONCURRENT_STREAMS")); err == nil && value > 0 {
+		maxStreams = value
+	}
+
+	return &PlaybackSessionService{sessions: sessions, maxStreams: maxStreams}
+}
+
+// MaxStreams is how many sessions an account can have at once
+func (s *PlaybackSessionService) MaxStreams() int {
+	return s.maxStreams
+}
+
+// Start opens a session, or moves an active one of the same user to what
+// session now describes when session.SessionID is set. When the account is
+// at its limit the session isn't opened, and the sessions in the way are
+// returned with ErrStreamLimitReached.
+func (s *PlaybackSessionService) Start(ctx context.Context, session *models.PlaybackSession) ([]models.PlaybackSession, error) {
+	now := time.Now()
+	session.LastHeartbeat = now
+	session.ExpiresAt = now.Add(playbackSessionTimeout)
+
+	if session.SessionID != "" {
+		filter := bson.M{"session_id": session.SessionID, "user_id": session.UserID, "expires_at": bson.M{"$gt": now}}
+		update := bson.M{
+			"$set": bson.M{
+				"tmdb_id":        session.TMDBID,
+				"season":         session.Season,
+				"episode":        session.Episode,
+				"device_name":    session.DeviceName,
+				"user_agent":     session.UserAgent,
+				"ip_address":     session.IPAddress,
+				"last_heartbeat": session.LastHeartbeat,
+				"expires_at":     session.ExpiresAt,
+			},
+		}
+		err := s.sessions.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(session)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrPlaybackSessionNotFound
+		}
+		return nil, err
+	}
+
+	session.SessionID = bson.NewObjectID().Hex()
+	session.StartedAt = now
+	if _, err := s.sessions.InsertOne(ctx, session); err != nil {
+		return nil, err
+	}
+
+	// Insert first and check afterwards, so two devices starting at once
+	// can't both slip under the limit. The oldest sessions keep their place.
+	active, err := s.Active(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i, other := range active {
+		if other.SessionID != session.SessionID {
+			continue
+		}
+		if i < s.maxStreams {
+			return nil, nil
+		}
+		if _, err = s.sessions.DeleteOne(ctx, bson.M{"session_id": session.SessionID}); err != nil {
+			return nil, err
+		}
+		return append(active[:i:i], active[i+1:]...), ErrStreamLimitReached
+	}
+	return nil, nil
+}
+
+// Active returns a user's live sessions, oldest first
+func (s *PlaybackSessionService) Active(ctx context.Context, userID string) ([]models.PlaybackSession, error) {
+	filter := bson.M{"user_id": userID, "expires_at": bson.M{"$gt": time.Now()}}
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}, {Key: "session_id", Value: 1}})
+
+	cursor, err := s.sessions.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.PlaybackSession{}
+	if err = cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Heartbeat keeps a live session going
+func (s *PlaybackSessionService) Heartbeat(ctx context.Context, userID, sessionID string) (*models.PlaybackSession, error) {
+	now := time.Now()
+	filter := bson.M{"session_id": sessionID, "user_id": userID, "expires_at": bson.M{"$gt": now}}
+	update := bson.M{"$set": bson.M{"last_heartbeat": now, "expires_at": now.Add(playbackSessionTimeout)}}
+
+	var session models.PlaybackSession
+	err := s.sessions.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPlaybackSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// End stops one of a user's sessions, whether from the device itself or
+// from another device kicking it off
+func (s *PlaybackSessionService) End(ctx context.Context, userID, sessionID string) error {
+	result, err := s.sessions.DeleteOne(ctx, bson.M{"session_id": sessionID, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrPlaybackSessionNotFound
+	}
+	return nil
+}
+
+// FindActive returns a session if it is still live, or nil
+func (s *PlaybackSessionService) FindActive(ctx context.Context, sessionID string) (*models.PlaybackSession, error) {
+	filter := bson.M{"session_id": sessionID, "expires_at": bson.M{"$gt": time.Now()}}
+	var session models.PlaybackSession
+	err := s.sessions.FindOne(ctx, filter).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrTitleNotFound  = errors.New("title not found")
+	ErrRatingNotFound = errors.New("rating not found")
+)
+
+// RatingResult holds the aggregates of a title after a rating change
+type RatingResult struct {
+	AverageRating float64 `bson:"average_rating" json:"average_rating"`
+	TotalRatings  int     `bson:"total_ratings" json:"total_ratings"`
+	// Updated is true when an existing rating was replaced rather than added
+	Updated bool `bson:"-" json:"-"`
+}
+
+// RatingService stores one rating document per user and title, and keeps
+// rating_sum, total_ratings and average_rating on the title document.
+// Aggregates are moved by deltas, so concurrent ratings never overwrite each other.
+type RatingService struct {
+	ratings *mongo.Collection
+	titles  *mongo.Collection
+	key     string
+}
+
+// NewRatingService creates a rating service; key is the field identifying the
+// title in both collections (tmdb_id for media, imdb_id for movies)
+func NewRatingService(ratings, titles *mongo.Collection, key string) *RatingService {
+	return &RatingService{
+		ratings: ratings,
+		titles:  titles,
+		key:     key,
+	}
+}
+
+// Rate adds the user's rating for a title or replaces the one they already gave
+func (s *RatingService) Rate(ctx context.Context, titleID any, userID string, rating float64) (*RatingResult, error) {
+	count, err := s.titles.CountDocuments(ctx, bson.M{s.key: titleID})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrTitleNotFound
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set":         bson.M{"rating": rating, "updated_at": now},
+		"$setOnInsert": bson.M{s.key: titleID, "user_id": userID, "created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous models.Rating
+	err = s.ratings.FindOneAndUpdate(ctx, bson.M{s.key: titleID, "user_id": userID}, update, opts).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent upsert for the same user won the insert; ours now matches it
+		err = s.ratings.FindOneAndUpdate(ctx, bson.M{s.key: titleID, "user_id": userID}, update, opts).Decode(&previous)
+	}
+
+	switch {
+	case err == mongo.ErrNoDocuments:
+		sumDelta, countDelta := ratingDelta(nil, &rating)
+		return s.applyDelta(ctx, titleID, sumDelta, countDelta, false)
+	case err != nil:
+		return nil, err
+	default:
+		sumDelta, countDelta := ratingDelta(&previous.Rating, &rating)
+		return s.applyDelta(ctx, titleID, sumDelta, countDelta, true)
+	}
+}
+
+// Remove deletes the user's rating for a title
+func (s *RatingService) Remove(ctx context.Context, titleID any, userID string) (*RatingResult, error) {
+	var previous models.Rating
+	err := s.ratings.FindOneAndDelete(ctx, bson.M{s.key: titleID, "user_id": userID}).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRatingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sumDelta, countDelta := ratingDelta(&previous.Rating, nil)
+	return s.applyDelta(ctx, titleID, sumDelta, countDelta, false)
+}
+
+// Recompute rebuilds the aggregates of every title from the raw ratings
+func (s *RatingService) Recompute(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         s.ratings.Name(),
+			"localField":   s.key,
+			"foreignField": s.key,
+			"as":           "raw_ratings",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"rating_sum":    bson.M{"$sum": "$raw_ratings.rating"},
+			"total_ratings": bson.M{"$size": "$raw_ratings"},
+			"average_rating": bson.M{"$ifNull": bson.A{
+				bson.M{"$avg": "$raw_ratings.rating"}, 0.0,
+			}},
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           s.titles.Name(),
+			"on":             "_id",
+			"whenMatched":    "merge",
+			"whenNotMatched": "discard",
+		}}},
+	}
+
+	cursor, err := s.titles.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
+
+// ratingDelta is how far a title's rating sum and count move when a user's
+// rating goes from previous to current; nil means no rating
+func ratingDelta(previous, current *float64) (float64, int) {
+	sumDelta, countDelta := 0.0, 0
+	if previous != nil {
+		sumDelta -= *previous
+		countDelta--
+	}
+	if current != nil {
+		sumDelta += *current
+		countDelta++
+	}
+	return sumDelta, countDelta
+}
+
+// ratingDeltaPipeline moves a title's aggregates by a delta in one update, so
+// concurrent changes add up. The sum is reset once no ratings are left, so
+// floating point leftovers can't build up.
+func ratingDeltaPipeline(sumDelta float64, countDelta int, now time.Time) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"rating_sum":    bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$rating_sum", 0.0}}, sumDelta}},
+			"total_ratings": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$total_ratings", 0}}, countDelta}},
+			"updated_at":    now,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"rating_sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$total_ratings", 0}}, "$rating_sum", 0.0}},
+			"average_rating": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{"$total_ratings", 0}},
+				bson.M{"$divide": bson.A{"$rating_sum", "$total_ratings"}},
+				0.0,
+			}},
+		}}},
+	}
+}
+
+func (s *RatingService) applyDelta(ctx context.Context, titleID any, sumDelta float64, countDelta int, updated bool) (*RatingResult, error) {
+	pipeline := ratingDeltaPipeline(sumDelta, countDelta, time.Now())
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"average_rating": 1, "total_ratings": 1})
+
+	var result RatingResult
+	err := s.titles.FindOneAndUpdate(ctx, bson.M{s.key: titleID}, pipeline, opts).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTitleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Updated = updated
+	return &result, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// applyPipeline runs an update pipeline of $set stages against a document,
+// evaluating the few expression operators the rating pipeline uses
+func applyPipeline(t *testing.T, doc bson.M, pipeline mongo.Pipeline) {
+	t.Helper()
+	for _, stage := range pipeline {
+		if len(stage) != 1 || stage[0].Key != "$set" {
+			t.Fatalf("unsupported stage %v", stage)
+		}
+		// Every field of a $set stage sees the document as it was before the stage
+		values := bson.M{}
+		for field, expr := range stage[0].Value.(bson.M) {
+			values[field] = evalExpr(t, doc, expr)
+		}
+		for field, value := range values {
+			doc[field] = value
+		}
+	}
+}
+
+func evalExpr(t *testing.T, doc bson.M, expr any) any {
+	t.Helper()
+	switch e := expr.(type) {
+	case string:
+		if len(e) > 0 && e[0] == '$' {
+			return doc[e[1:]]
+		}
+		return e
+	case bson.M:
+		for op, arg := range e {
+			args := arg.(bson.A)
+			switch op {
+			case "$add":
+				sum, isFloat := 0.0, false
+				for _, a := range args {
+					value := evalExpr(t, doc, a)
+					_, f := value.(float64)
+					isFloat = isFloat || f
+					sum += toFloat(t, value)
+				}
+				if isFloat {
+					return sum
+				}
+				return int(sum)
+			case "$ifNull":
+				if value := evalExpr(t, doc, args[0]); value != nil {
+					return value
+				}
+				return evalExpr(t, doc, args[1])
+			case "$cond":
+				if evalExpr(t, doc, args[0]).(bool) {
+					return evalExpr(t, doc, args[1])
+				}
+				return evalExpr(t, doc, args[2])
+			case "$gt":
+				return toFloat(t, evalExpr(t, doc, args[0])) > toFloat(t, evalExpr(t, doc, args[1]))
+			case "$divide":
+				return toFloat(t, evalExpr(t, doc, args[0])) / toFloat(t, evalExpr(t, doc, args[1]))
+			}
+			t.Fatalf("unsupported operator %s", op)
+		}
+	}
+	return expr
+}
+
+func toFloat(t *testing.T, value any) float64 {
+	t.Helper()
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	t.Fatalf("%v is not a number", value)
+	return 0
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func TestRatingDelta(t *testing.T) {
+	tests := []struct {
+		previous, current *float64
+		sum               float64
+		count             int
+	}{
+		{nil, ptr(8), 8, 1},
+		{ptr(8), ptr(5.5), -2.5, 0},
+		{ptr(8), ptr(8), 0, 0},
+		{ptr(5.5), nil, -5.5, -1},
+	}
+
+	for _, tt := range tests {
+		sum, count := ratingDelta(tt.previous, tt.current)
+		if sum != tt.sum || count != tt.count {
+			t.Errorf("ratingDelta(%v, %v) = %v, %d; want %v, %d", tt.previous, tt.current, sum, count, tt.sum, tt.count)
+		}
+	}
+}
+
+func TestRatingDeltaPipeline(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// A title from before ratings were aggregated has none of the fields
+	title := bson.M{"tmdb_id": 550}
+
+	type change struct {
+		name              string
+		previous, current *float64
+		sum, average      float64
+		total             int
+	}
+	changes := []change{
+		{"first rating", nil, ptr(8), 8, 8, 1},
+		{"second rating", nil, ptr(0.1), 8.1, 4.05, 2},
+		{"changed rating", ptr(8), ptr(0.2), 0.3, 0.15, 2},
+		{"one removed", ptr(0.1), nil, 0.2, 0.2, 1},
+		{"last removed", ptr(0.2), nil, 0, 0, 0},
+		{"rated again", nil, ptr(6), 6, 6, 1},
+	}
+
+	for _, c := range changes {
+		sumDelta, countDelta := ratingDelta(c.previous, c.current)
+		applyPipeline(t, title, ratingDeltaPipeline(sumDelta, countDelta, now))
+
+		got := fmt.Sprintf("sum %.6f, total %v, average %.6f", title["rating_sum"], title["total_ratings"], title["average_rating"])
+		want := fmt.Sprintf("sum %.6f, total %v, average %.6f", c.sum, c.total, c.average)
+		if got != want {
+			t.Errorf("%s: %s, want %s", c.name, got, want)
+		}
+		// The deltas leave a rounding error behind unless the sum is reset
+		if c.total == 0 && title["rating_sum"] != 0.0 {
+			t.Errorf("%s: sum %v, want exactly 0", c.name, title["rating_sum"])
+		}
+	}
+
+	if title["updated_at"] != now {
+		t.Errorf("updated_at = %v, want %v", title["updated_at"], now)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrRelationNotFound = errors.New("user is not in this list")
+
+// RelationService manages users' block and mute lists
+type RelationService struct {
+	relations *mongo.Collection
+}
+
+func NewRelationService(relations *mongo.Collection) *RelationService {
+	return &RelationService{relations: relations}
+}
+
+// Add puts targetID on userID's list. Adding an existing entry is a no-op.
+func (s *RelationService) Add(ctx context.Context, userID, targetID string, relationType models.RelationType) error {
+	filter := bson.M{"user_id": userID, "target_id": targetID, "type": relationType}
+	update := bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}}
+
+	_, err := s.relations.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// Remove takes targetID off userID's list
+func (s *RelationService) Remove(ctx context.Context, userID, targetID string, relationType models.RelationType) error {
+	result, err := s.relations.DeleteOne(ctx, bson.M{"user_id": userID, "target_id": targetID, "type": relationType})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrRelationNotFound
+	}
+	return nil
+}
+
+// List returns userID's block or mute list, newest first
+func (s *RelationService) List(ctx context.Context, userID string, relationType models.RelationType) ([]models.UserRelation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := s.relations.Find(ctx, bson.M{"user_id": userID, "type": relationType}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	relations := []models.UserRelation{}
+	if err = cursor.All(ctx, &relations); err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
+// HiddenAuthors returns the users whose content viewerID shouldn't see: those
+// the viewer blocked or muted, and those who blocked the viewer
+func (s *RelationService) HiddenAuthors(ctx context.Context, viewerID string) ([]string, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"user_id": viewerID},
+		bson.M{"target_id": viewerID, "type": models.RelationBlock},
+	}}
+	cursor, err := s.relations.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var relations []models.UserRelation
+	if err = cursor.All(ctx, &relations); err != nil {
+		return nil, err
+	}
+
+	authors := make([]string, 0, len(relations))
+	for _, relation := range relations {
+		if relation.UserID == viewerID {
+			authors = append(authors, relation.TargetID)
+		} else {
+			authors = append(authors, relation.UserID)
+		}
+	}
+	return authors, nil
+}
+
+// Blocked reports whether either user has blocked the other
+func (s *RelationService) Blocked(ctx context.Context, userID, otherID string) (bool, error) {
+	filter := bson.M{"type": models.RelationBlock, "$or": bson.A{
+		bson.M{"user_id": userID, "target_id": otherID},
+		bson.M{"user_id": otherID, "target_id": userID},
+	}}
+	count, err := s.relations.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
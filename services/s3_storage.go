@@ -0,0 +1,437 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+	// Files bigger than this are sent in parts of this size. S3 takes at most
+	// 10,000 parts, which puts the limit at 640GB.
+	s3PartSize       = 64 << 20
+	unsignedPayload  = "UNSIGNED-PAYLOAD"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	amzDateFormat    = "20060102T150405Z"
+)
+
+// S3Storage keeps files in a bucket of an S3-compatible service such as AWS
+// S3 or MinIO. Requests use path-style addressing and AWS Signature
+// Version 4; bodies are sent unsigned, relying on TLS for integrity.
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	// partSize is s3PartSize, smaller in tests
+	partSize int64
+}
+
+func NewS3Storage(endpoint, region, bucket, accessKey, secretKey string) (*S3Storage, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("S3 endpoint %q must be an http or https URL", endpoint)
+	}
+	if bucket == "" || accessKey == "" || secretKey == "" {
+		return nil, errors.New("S3 storage needs a bucket, an access key ID and a secret access key")
+	}
+
+	return &S3Storage{
+		endpoint:  parsed,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{},
+		partSize:  s3PartSize,
+	}, nil
+}
+
+// s3Error is the error document S3 sends with failed requests
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// uriEncode percent-encodes everything but unreserved characters, the way
+// Signature Version 4 expects. Slashes are kept when encoding paths.
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if 'A' <= ch && ch <= 'Z' || 'a' <= ch && ch <= 'z' || '0' <= ch && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || (ch == '/' && keepSlash) {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery sorts and encodes query parameters for signing
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, false)+"="+uriEncode(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// signature signs a request with Signature Version 4. headers maps lowercase
+// names to values and must include host.
+func (s *S3Storage) signature(method, uri, query string, headers map[string]string, payloadHash string, now time.Time) (signedHeaders, signature string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{method, uri, query, canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	date := now.Format("20060102")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format(amzDateFormat), s.scope(date), hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func (s *S3Storage) scope(date string) string {
+	return date + "/" + s.region + "/s3/aws4_request"
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// objectPath is the encoded path of an object, or of the bucket when key is empty
+func (s *S3Storage) objectPath(key string) string {
+	p := strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + "/" + uriEncode(s.bucket, false)
+	if key != "" {
+		p += "/" + uriEncode(key, true)
+	}
+	return p
+}
+
+// do sends a signed request. Responses outside 2xx are turned into errors,
+// with a missing object reported as ErrObjectNotFound.
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64) (*http.Response, error) {
+	if key != "" && !validKey(key) {
+		return nil, fmt.Errorf("invalid storage key %q", key)
+	}
+
+	escapedPath, rawQuery := s.objectPath(key), canonicalQuery(query)
+	target := s.endpoint.Scheme + "://" + s.endpoint.Host + escapedPath
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	if body != nil && size == 0 {
+		// Any other reader with a zero length is sent chunked, which S3 refuses
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	payloadHash := unsignedPayload
+	if body == nil {
+		payloadHash = emptyPayloadHash
+	}
+	now := time.Now().UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" {
+			signed[lower] = req.Header.Get(name)
+		}
+	}
+	signedHeaders, signature := s.signature(method, escapedPath, rawQuery, signed, payloadHash, now)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(now.Format("20060102")), signedHeaders, signature))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, ErrObjectNotFound
+	}
+	var failure s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(data, &failure) == nil && failure.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s: %s: %s", method, key, failure.Code, failure.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s", method, key, resp.Status)
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	resp, err := s.do(ctx, http.MethodPut, key, nil, nil, io.LimitReader(r, size), size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PutFile uploads a local file, in parts when it is large, then deletes it
+func (s *S3Storage) PutFile(ctx context.Context, key, name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if stat.Size() <= s.partSize {
+		err = s.Put(ctx, key, file, stat.Size())
+	} else {
+		err = s.putMultipart(ctx, key, file, stat.Size())
+	}
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(name)
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putMultipart sends a file with the multipart upload API, aborting the
+// upload if any part fails so S3 doesn't keep the parts around
+func (s *S3Storage) putMultipart(ctx context.Context, key string, file io.ReaderAt, size int64) error {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&initiated)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	err = s.sendParts(ctx, key, initiated.UploadID, file, size)
+	if err != nil {
+		if resp, abortErr := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {initiated.UploadID}}, nil, nil, 0); abortErr == nil {
+			resp.Body.Close()
+		}
+	}
+	return err
+}
+
+func (s *S3Storage) sendParts(ctx context.Context, key, uploadID string, file io.ReaderAt, size int64) error {
+	var parts []completedPart
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+s.partSize, number+1 {
+		length := min(s.partSize, size-offset)
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, io.NewSectionReader(file, offset, length), length)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+	}
+
+	complete, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, bytes.NewReader(complete), int64(len(complete)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// A completion can fail after the 200 has been sent, in which case the
+	// body is an error document
+	var failure s3Error
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if xml.Unmarshal(data, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("S3 completing %s: %s: %s", key, failure.Code, failure.Message)
+	}
+	return nil
+}
+
+func s3ObjectInfo(key string, header http.Header) ObjectInfo {
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(header.Get("Last-Modified"))
+	return ObjectInfo{Key: key, Size: size, ModTime: modTime, ETag: header.Get("ETag")}
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, nil, 0)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return resp.Body, s3ObjectInfo(key, resp.Header), nil
+}
+
+func (s *S3Storage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		if length == 0 {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, http.Header{"Range": {byteRange}}, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+		// The server ignored the range; skip to the offset ourselves
+		if _, err = io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, nil, 0)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp.Body.Close()
+	return s3ObjectInfo(key, resp.Header), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil, 0)
+	if errors.Is(err, ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+				ETag         string    `xml:"ETag"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{Key: object.Key, Size: object.Size, ModTime: object.LastModified, ETag: object.ETag})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Presign builds a query-signed GET URL for the object
+func (s *S3Storage) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+
+	now := time.Now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.accessKey + "/" + s.scope(now.Format("20060102"))},
+		"X-Amz-Date":          {now.Format(amzDateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(expires.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	escapedPath := s.objectPath(key)
+	rawQuery := canonicalQuery(query)
+	_, signature := s.signature(http.MethodGet, escapedPath, rawQuery, map[string]string{"host": s.endpoint.Host}, unsignedPayload, now)
+
+	return s.endpoint.Scheme + "://" + s.endpoint.Host + escapedPath + "?" + rawQuery + "&X-Amz-Signature=" + signature, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"cmp"
+	"errors"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// MaxVideoSources caps how many sources a title can have
+const MaxVideoSources = 20
+
+var ErrNoPlayableSource = errors.New("none of this title's video sources can be played with the codecs and containers given")
+
+// SourceContainer guesses a source's container from the extension of its
+// URL or storage key, e.g. "mp4", or "hls" for a playlist
+func SourceContainer(location string) string {
+	if i := strings.IndexAny(location, "?#"); i >= 0 {
+		location = location[:i]
+	}
+	container := strings.ToLower(strings.TrimPrefix(path.Ext(location), "."))
+	if container == "m3u8" {
+		return "hls"
+	}
+	return container
+}
+
+// DefaultSources turns a title's single video, from before it had sources,
+// into sources. The stored file is the default, as it was preferred for
+// playback; URLs that aren't http(s), such as HLS master paths, are left out.
+// The slice is never nil, so a title without a playable video stores an
+// empty array, meaning it has no sources, rather than null.
+func DefaultSources(videoURL, videoFile string) []models.VideoSource {
+	sources := []models.VideoSource{}
+	if videoFile != "" {
+		sources = append(sources, models.VideoSource{
+			SourceID:   bson.NewObjectID().Hex(),
+			Container:  SourceContainer(videoFile),
+			StorageKey: videoFile,
+		})
+	}
+	if strings.HasPrefix(videoURL, "http://") || strings.HasPrefix(videoURL, "https://") {
+		sources = append(sources, models.VideoSource{
+			SourceID:  bson.NewObjectID().Hex(),
+			Container: SourceContainer(videoURL),
+			URL:       videoURL,
+		})
+	}
+	if len(sources) > 0 {
+		sources[0].Default = true
+	}
+	return sources
+}
+
+// supports reports whether a capability list allows value. An empty list,
+// or a source that doesn't say, is taken to be playable.
+func supports(list []string, value string) bool {
+	if len(list) == 0 || value == "" {
+		return true
+	}
+	return slices.ContainsFunc(list, func(item string) bool {
+		return strings.EqualFold(item, value)
+	})
+}
+
+// SupportsHLS reports whether a player can play HLS renditions, which it
+// is assumed to unless it lists containers without "hls"
+func SupportsHLS(hints models.PlaybackCapabilities) bool {
+	return len(hints.Containers) == 0 || supports(hints.Containers, "hls")
+}
+
+// sameLanguage compares the primary language subtags of two BCP 47 tags, so
+// "en-GB" matches "en"
+func sameLanguage(a, b string) bool {
+	a, _, _ = strings.Cut(a, "-")
+	b, _, _ = strings.Cut(b, "-")
+	return strings.EqualFold(a, b)
+}
+
+// compareQuality orders sources from the lowest quality to the highest
+func compareQuality(a, b models.VideoSource) int {
+	return cmp.Or(cmp.Compare(a.Height, b.Height), cmp.Compare(a.Bitrate, b.Bitrate))
+}
+
+// SelectSource picks the source a player should stream:
+//
+//   - sources in a codec or container the player doesn't list are ruled
+//     out, failing with ErrNoPlayableSource when nothing is left;
+//   - of the rest, those within MaxHeight and MaxBitrate are kept, or the
+//     lowest quality one when none are;
+//   - sources in the player's language are preferred;
+//   - without a quality cap the default source is picked if it is still in
+//     the running, otherwise the highest quality one.
+func SelectSource(sources []models.VideoSource, hints models.PlaybackCapabilities) (*models.VideoSource, error) {
+	var playable []models.VideoSource
+	for _, source := range sources {
+		if supports(hints.Codecs, source.Codec) && supports(hints.Containers, source.Container) {
+			playable = append(playable, source)
+		}
+	}
+	if len(playable) == 0 {
+		return nil, ErrNoPlayableSource
+	}
+
+	var candidates []models.VideoSource
+	for _, source := range playable {
+		if (hints.MaxHeight == 0 || source.Height == 0 || source.Height <= hints.MaxHeight) &&
+			(hints.MaxBitrate == 0 || source.Bitrate == 0 || source.Bitrate <= hints.MaxBitrate) {
+			candidates = append(candidates, source)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = []models.VideoSource{slices.MinFunc(playable, compareQuality)}
+	}
+
+	if hints.Language != "" {
+		var inLanguage []models.VideoSource
+		for _, source := range candidates {
+			if sameLanguage(source.Language, hints.Language) {
+				inLanguage = append(inLanguage, source)
+			}
+		}
+		if len(inLanguage) > 0 {
+			candidates = inLanguage
+		}
+	}
+
+	if hints.MaxHeight == 0 && hints.MaxBitrate == 0 {
+		if i := slices.IndexFunc(candidates, func(source models.VideoSource) bool { return source.Default }); i >= 0 {
+			return &candidates[i], nil
+		}
+	}
+	best := slices.MaxFunc(candidates, func(a, b models.VideoSource) int {
+		// The default source wins ties
+		return cmp.Or(compareQuality(a, b), compareDefault(a, b))
+	})
+	return &best, nil
+}
+
+func compareDefault(a, b models.VideoSource) int {
+	switch {
+	case a.Default == b.Default:
+		return 0
+	case a.Default:
+		return 1
+	}
+	return -1
+}
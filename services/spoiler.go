@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+)
+
+// spoilerMarker opens and closes an inline spoiler: "the ||butler|| did it"
+const spoilerMarker = "||"
+
+// ParseSpoilers removes ||spoiler|| markup from text and returns the plain text
+// with the ranges that were marked. Offsets count characters (code points) in
+// the returned text. An unmatched marker is kept as literal text and empty
+// pairs are dropped.
+func ParseSpoilers(text string) (string, []models.SpoilerRange) {
+	var builder strings.Builder
+	var ranges []models.SpoilerRange
+	length := 0
+
+	rest := text
+	for {
+		open := strings.Index(rest, spoilerMarker)
+		if open < 0 {
+			break
+		}
+		closing := strings.Index(rest[open+len(spoilerMarker):], spoilerMarker)
+		if closing < 0 {
+			break
+		}
+
+		before := rest[:open]
+		inner := rest[open+len(spoilerMarker) : open+len(spoilerMarker)+closing]
+		rest = rest[open+2*len(spoilerMarker)+closing:]
+
+		builder.WriteString(before)
+		length += len([]rune(before))
+
+		if strings.TrimSpace(inner) == "" {
+			builder.WriteString(inner)
+			length += len([]rune(inner))
+			continue
+		}
+
+		start := length
+		builder.WriteString(inner)
+		length += len([]rune(inner))
+		ranges = append(ranges, models.SpoilerRange{Start: start, End: length})
+	}
+	builder.WriteString(rest)
+
+	return builder.String(), ranges
+}
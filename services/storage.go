@@ -0,0 +1,397 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultMediaStorageDir = "media"
+
+var (
+	ErrObjectNotFound      = errors.New("object not found")
+	ErrPresignNotSupported = errors.New("this storage backend can't presign URLs")
+)
+
+// MediaStorageDir is the directory media files are kept in, set by MEDIA_STORAGE_DIR
+func MediaStorageDir() string {
+	if dir := os.Getenv("MEDIA_STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return defaultMediaStorageDir
+}
+
+// ObjectInfo describes a stored file
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	// ETag is a quoted entity tag that changes whenever the content does
+	ETag string
+}
+
+// Storage keeps media files under slash-separated keys such as
+// "uploads/550/abc.mp4". Keys can't contain "." or ".." segments.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// PutFile moves a local file into storage under key, replacing any
+	// existing object. The local file is gone afterwards.
+	PutFile(ctx context.Context, key, path string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+	// GetRange reads length bytes from offset; a negative length reads to the end
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns every object whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Presign returns a URL that fetches the object without credentials
+	// until it expires, or ErrPresignNotSupported
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// NewStorageFromEnv creates the storage backend picked by STORAGE_BACKEND:
+// "local" (the default) keeps files in MEDIA_STORAGE_DIR and "s3" keeps
+// them in an S3-compatible bucket configured by the S3_* variables
+func NewStorageFromEnv() (Storage, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "local":
+		return NewLocalStorage(MediaStorageDir()), nil
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = defaultS3Region
+		}
+		return NewS3Storage(os.Getenv("S3_ENDPOINT"), region, os.Getenv("S3_BUCKET"), os.Getenv("S3_ACCESS_KEY_ID"), os.Getenv("S3_SECRET_ACCESS_KEY"))
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", backend)
+	}
+}
+
+// validKey reports whether key is a relative slash-separated path with no
+// empty, "." or ".." segments
+func validKey(key string) bool {
+	return key != "" && filepath.IsLocal(key) && path.Clean(key) == key && !strings.Contains(key, `\`)
+}
+
+// LocalStorage keeps files in a directory on the local filesystem. Every
+// access goes through os.Root, so symlinks can't lead outside the directory.
+type LocalStorage struct {
+	dir string
+}
+
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{dir: dir}
+}
+
+// tempPrefix marks files being written; List leaves them out
+const tempPrefix = ".put-"
+
+func (s *LocalStorage) openRoot() (*os.Root, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenRoot(s.dir)
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	root, err := s.openRoot()
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	dir := path.Dir(key)
+	if err = root.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write next to the target and rename, so readers never see a partial file
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	temp := path.Join(dir, tempPrefix+hex.EncodeToString(suffix))
+	file, err := root.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer root.Remove(temp)
+
+	written, err := io.Copy(file, io.LimitReader(r, size))
+	if err == nil && written != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return root.Rename(temp, key)
+}
+
+func (s *LocalStorage) PutFile(ctx context.Context, key, name string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	root, err := s.openRoot()
+	if err != nil {
+		return err
+	}
+	err = root.MkdirAll(path.Dir(key), 0o755)
+	root.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(name, filepath.Join(s.dir, filepath.FromSlash(key)))
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	// The file is on another filesystem, so it has to be copied
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err = s.Put(ctx, key, file, info.Size()); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// open opens the regular file stored under key
+func (s *LocalStorage) open(key string) (*os.File, ObjectInfo, error) {
+	if !validKey(key) {
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+	root, err := os.OpenRoot(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, ObjectInfo{}, err
+	}
+	if !stat.Mode().IsRegular() {
+		file.Close()
+		return nil, ObjectInfo{}, ErrObjectNotFound
+	}
+	return file, localObjectInfo(key, stat), nil
+}
+
+func localObjectInfo(key string, stat fs.FileInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:     key,
+		Size:    stat.Size(),
+		ModTime: stat.ModTime(),
+		ETag:    fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+	}
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error) {
+	return s.open(key)
+}
+
+func (s *LocalStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	file, info, err := s.open(key)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		length = info.Size - offset
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(file, offset, length), file}, nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	file, info, err := s.open(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	file.Close()
+	return info, nil
+}
+
+// Delete removes the file and any directories it leaves empty
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	root, err := os.OpenRoot(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if err = root.Remove(key); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for dir := path.Dir(key); dir != "."; dir = path.Dir(dir) {
+		if root.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	root, err := os.OpenRoot(s.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+
+	// Only the deepest directory the prefix names needs walking
+	start := "."
+	if i := strings.LastIndex(prefix, "/"); i > 0 && validKey(prefix[:i]) {
+		start = prefix[:i]
+	}
+
+	var objects []ObjectInfo
+	err = fs.WalkDir(root.FS(), start, func(key string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fs.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() || !strings.HasPrefix(key, prefix) || strings.HasPrefix(entry.Name(), tempPrefix) {
+			return nil
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, localObjectInfo(key, stat))
+		return nil
+	})
+	return objects, err
+}
+
+func (s *LocalStorage) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// DeletePrefix removes every object below prefix, such as the files of an
+// asset that has been replaced
+func DeletePrefix(ctx context.Context, storage Storage, prefix string) error {
+	objects, err := storage.List(ctx, strings.TrimSuffix(prefix, "/")+"/")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err = storage.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenObject opens a stored object for reading with seeking, as
+// http.ServeContent needs. Each read after a seek fetches the rest of the
+// object from that point with one range read.
+func OpenObject(ctx context.Context, storage Storage, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+	info, err := storage.Stat(ctx, key)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return &objectReader{ctx: ctx, storage: storage, key: key, size: info.Size}, info, nil
+}
+
+type objectReader struct {
+	ctx     context.Context
+	storage Storage
+	key     string
+	size    int64
+	offset  int64
+	body    io.ReadCloser
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.storage.GetRange(r.ctx, r.key, r.offset, r.size-r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the object")
+	}
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// srtTiming matches "00:01:02,500 --> 00:01:04,000", with any position
+	// coordinates after it; some files use a dot instead of the comma
+	srtTiming = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{3})`)
+	// vttTiming matches a WebVTT cue timing line, where hours are optional
+	vttTiming = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})\s+-->\s+(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})`)
+	// srtMarkup is formatting WebVTT has no equivalent for: font tags and
+	// ASS-style overrides such as {\an8}
+	srtMarkup = regexp.MustCompile(`(?i)</?font[^>]*>|\{\\[^}]*\}`)
+)
+
+// ParseSubtitles reads an SRT or WebVTT file and returns it as WebVTT, with
+// the end time of its last cue. SRT is converted; WebVTT is checked and has
+// its line endings normalised. Files must be UTF-8.
+func ParseSubtitles(data []byte) ([]byte, time.Duration, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, 0, errors.New("subtitles must be UTF-8 text")
+	}
+	text := strings.ReplaceAll(strings.ReplaceAll(string(data), "\r\n", "\n"), "\r", "\n")
+
+	if strings.HasPrefix(text, "WEBVTT") {
+		return checkWebVTT(text)
+	}
+	return convertSRT(text)
+}
+
+func convertSRT(text string) ([]byte, time.Duration, error) {
+	var out strings.Builder
+	out.WriteString("WEBVTT\n")
+
+	var duration time.Duration
+	cues := 0
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if len(lines) == 1 && strings.TrimSpace(lines[0]) == "" {
+			continue
+		}
+		// The cue number is optional in practice
+		if len(lines) > 1 && !srtTiming.MatchString(lines[0]) {
+			lines = lines[1:]
+		}
+
+		match := srtTiming.FindStringSubmatch(strings.TrimSpace(lines[0]))
+		if match == nil {
+			return nil, 0, fmt.Errorf("cue %d has no valid timing line", cues+1)
+		}
+		start, end := cueTime(match[1:5]), cueTime(match[5:9])
+		if end < start {
+			return nil, 0, fmt.Errorf("cue %d ends before it starts", cues+1)
+		}
+		duration = max(duration, end)
+
+		fmt.Fprintf(&out, "\n%s --> %s\n", formatCueTime(start), formatCueTime(end))
+		for _, line := range lines[1:] {
+			line = srtMarkup.ReplaceAllString(line, "")
+			// "-->" would end the cue text in WebVTT
+			out.WriteString(strings.ReplaceAll(line, "-->", "->") + "\n")
+		}
+		cues++
+	}
+	if cues == 0 {
+		return nil, 0, errors.New("the file has no subtitles")
+	}
+
+	return []byte(out.String()), duration, nil
+}
+
+func checkWebVTT(text string) ([]byte, time.Duration, error) {
+	header, _, _ := strings.Cut(text, "\n")
+	if header != "WEBVTT" && !strings.HasPrefix(header, "WEBVTT ") && !strings.HasPrefix(header, "WEBVTT\t") {
+		return nil, 0, errors.New("the WEBVTT header is malformed")
+	}
+
+	var duration time.Duration
+	cues := 0
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.Contains(line, "-->") {
+			continue
+		}
+		match := vttTiming.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			return nil, 0, fmt.Errorf("cue %d has an invalid timing line", cues+1)
+		}
+		start, end := cueTime(match[1:5]), cueTime(match[5:9])
+		if end < start {
+			return nil, 0, fmt.Errorf("cue %d ends before it starts", cues+1)
+		}
+		duration = max(duration, end)
+		cues++
+	}
+	if cues == 0 {
+		return nil, 0, errors.New("the file has no subtitles")
+	}
+
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return []byte(text), duration, nil
+}
+
+// cueTime turns hours, minutes, seconds and milliseconds into a duration;
+// hours may be empty
+func cueTime(parts []string) time.Duration {
+	var values [4]int
+	for i, part := range parts {
+		values[i], _ = strconv.Atoi(part)
+	}
+	return time.Duration(values[0])*time.Hour + time.Duration(values[1])*time.Minute +
+		time.Duration(values[2])*time.Second + time.Duration(values[3])*time.Millisecond
+}
+
+// formatCueTime writes a WebVTT timestamp such as 01:02:03.456
+func formatCueTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// SubtitlePlaylist is the HLS media playlist for a subtitle track served as
+// one WebVTT file at uri
+func SubtitlePlaylist(uri string, duration time.Duration) string {
+	seconds := max(duration.Seconds(), 1)
+	return fmt.Sprintf("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:%.3f,\n%s\n#EXT-X-ENDLIST\n",
+		int(seconds+0.999), seconds, uri)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSubtitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		want     string
+		duration time.Duration
+	}{
+		{
+			name:     "numbered srt",
+			in:       "1\n00:00:01,000 --> 00:00:02,500\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld\n",
+			want:     "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.000\nWorld\n",
+			duration: 4 * time.Second,
+		},
+		{
+			name:     "missing cue numbers",
+			in:       "00:00:01,000 --> 00:00:02,000\nOne\n\n2\n00:00:02,000 --> 00:00:03,000\nTwo\n",
+			want:     "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nOne\n\n00:00:02.000 --> 00:00:03.000\nTwo\n",
+			duration: 3 * time.Second,
+		},
+		{
+			name:     "arrow in cue text",
+			in:       "1\n00:00:01,000 --> 00:00:02,000\nleft --> right\n",
+			want:     "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nleft -> right\n",
+			duration: 2 * time.Second,
+		},
+		{
+			name:     "crlf and bom",
+			in:       "\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:02,000\r\nLine one\r\nLine two\r\n\r\n",
+			want:     "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nLine one\nLine two\n",
+			duration: 2 * time.Second,
+		},
+		{
+			name:     "dot separator, position and markup",
+			in:       "1\n01:02:03.004 --> 01:02:05.000 X1:10 X2:20\n<font color=\"red\">{\\an8}<i>Top</i></font>\n",
+			want:     "WEBVTT\n\n01:02:03.004 --> 01:02:05.000\n<i>Top</i>\n",
+			duration: time.Hour + 2*time.Minute + 5*time.Second,
+		},
+		{
+			name:     "webvtt kept",
+			in:       "WEBVTT - English\r\n\r\n00:01.000 --> 00:02.000\r\nHi\r\n\r\n01:00:00.000 --> 01:00:01.500 line:0\r\nBye",
+			want:     "WEBVTT - English\n\n00:01.000 --> 00:02.000\nHi\n\n01:00:00.000 --> 01:00:01.500 line:0\nBye\n",
+			duration: time.Hour + 1500*time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vtt, duration, err := ParseSubtitles([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(vtt) != tt.want {
+				t.Errorf("vtt = %q, want %q", vtt, tt.want)
+			}
+			if duration != tt.duration {
+				t.Errorf("duration = %v, want %v", duration, tt.duration)
+			}
+		})
+	}
+}
+
+func TestParseSubtitlesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"blank lines", "\n\n\n"},
+		{"not utf-8", "1\n00:00:01,000 --> 00:00:02,000\n\xff\xfe\n"},
+		{"no timing", "1\nHello\n"},
+		{"ends before start", "1\n00:00:05,000 --> 00:00:02,000\nBackwards\n"},
+		{"malformed webvtt header", "WEBVTTX\n\n00:01.000 --> 00:02.000\nHi\n"},
+		{"webvtt bad timing", "WEBVTT\n\n00:01,000 --> 00:02,000\nHi\n"},
+		{"webvtt without cues", "WEBVTT\n\nNOTE nothing here\n"},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := ParseSubtitles([]byte(tt.in)); err == nil {
+			t.Errorf("%s: ParseSubtitles succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ThumbnailsFile is the WebVTT thumbnails track stored next to the sprites
+const ThumbnailsFile = "thumbnails.vtt"
+
+// spriteQuality is the JPEG quality of sprite sheets; previews are small
+// and blurred by scaling anyway
+const spriteQuality = 75
+
+// frameExtensions are the image types frames can be extracted as
+var frameExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// SpriteLayout is how frames are packed into sprite sheets. A frame is taken
+// every Interval, scaled to TileWidth keeping the first frame's aspect
+// ratio, and sheets hold Columns × Rows tiles.
+type SpriteLayout struct {
+	Interval  time.Duration
+	TileWidth int
+	Columns   int
+	Rows      int
+}
+
+// SpriteSheet is one generated sprite image
+type SpriteSheet struct {
+	Name string
+	Data []byte
+}
+
+// ThumbnailSprites is the output of GenerateSprites
+type ThumbnailSprites struct {
+	Sheets     []SpriteSheet
+	VTT        []byte
+	TileHeight int
+	FrameCount int
+}
+
+// SpriteName is the file name of the nth sprite sheet
+func SpriteName(n int) string {
+	return fmt.Sprintf("sprite-%03d.jpg", n)
+}
+
+// ThumbnailsPath is the API path of the thumbnails track for a movie, or
+// for an episode when season and episode are set
+func ThumbnailsPath(tmdbID int, season, episode *int) string {
+	if season != nil && episode != nil {
+		return fmt.Sprintf("/media/%d/episodes/%d/%d/thumbnails/%s", tmdbID, *season, *episode, ThumbnailsFile)
+	}
+	return fmt.Sprintf("/media/%d/thumbnails/%s", tmdbID, ThumbnailsFile)
+}
+
+// ListFrames returns the frame images in dir in name order, which is the
+// order they were extracted in, e.g. by
+//
+//	ffmpeg -i video.mp4 -vf fps=1/10 dir/frame-%05d.jpg
+func ListFrames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && frameExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+			frames = append(frames, filepath.Join(dir, entry.Name()))
+		}
+	}
+	slices.Sort(frames)
+	if len(frames) == 0 {
+		return nil, errors.New("no JPEG or PNG frames found")
+	}
+	return frames, nil
+}
+
+// GenerateSprites packs frames into sprite sheets and writes the WebVTT
+// track pointing each stretch of the video at its tile, with URIs such as
+// sprite-000.jpg#xywh=160,0,160,90 relative to the track
+func GenerateSprites(frames []string, layout SpriteLayout) (*ThumbnailSprites, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("no frames to pack")
+	}
+	if layout.Interval <= 0 || layout.TileWidth <= 0 || layout.Columns <= 0 || layout.Rows <= 0 {
+		return nil, errors.New("invalid sprite layout")
+	}
+
+	result := &ThumbnailSprites{FrameCount: len(frames)}
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n")
+
+	perSheet := layout.Columns * layout.Rows
+	var sheet *image.RGBA
+	for i, name := range frames {
+		frame, err := decodeFrame(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(name), err)
+		}
+		if result.TileHeight == 0 {
+			bounds := frame.Bounds()
+			result.TileHeight = max(1, (layout.TileWidth*bounds.Dy()+bounds.Dx()/2)/bounds.Dx())
+		}
+
+		tile := i % perSheet
+		if tile == 0 {
+			tilesLeft := min(perSheet, len(frames)-i)
+			columns := min(layout.Columns, tilesLeft)
+			rows := (tilesLeft + layout.Columns - 1) / layout.Columns
+			sheet = image.NewRGBA(image.Rect(0, 0, columns*layout.TileWidth, rows*result.TileHeight))
+		}
+		x, y := tile%layout.Columns*layout.TileWidth, tile/layout.Columns*result.TileHeight
+		scaleInto(sheet, image.Rect(x, y, x+layout.TileWidth, y+result.TileHeight), frame)
+
+		start, end := time.Duration(i)*layout.Interval, time.Duration(i+1)*layout.Interval
+		fmt.Fprintf(&vtt, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", formatCueTime(start), formatCueTime(end),
+			SpriteName(len(result.Sheets)), x, y, layout.TileWidth, result.TileHeight)
+
+		if tile == perSheet-1 || i == len(frames)-1 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, sheet, &jpeg.Options{Quality: spriteQuality}); err != nil {
+				return nil, err
+			}
+			result.Sheets = append(result.Sheets, SpriteSheet{Name: SpriteName(len(result.Sheets)), Data: buf.Bytes()})
+		}
+	}
+
+	result.VTT = []byte(vtt.String())
+	return result, nil
+}
+
+// decodeFrame reads a frame image into RGBA, which scaleInto works on
+func decodeFrame(name string) (*image.RGBA, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, errors.New("empty image")
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba, nil
+}
+
+// scaleInto resizes src into the rect of dst, averaging the source pixels
+// each destination pixel covers
+func scaleInto(dst *image.RGBA, rect image.Rectangle, src *image.RGBA) {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := rect.Dx(), rect.Dy()
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, max((dy+1)*sh/dh, dy*sh/dh+1)
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, max((dx+1)*sw/dw, dx*sw/dw+1)
+			var sum [4]int
+			for y := y0; y < y1; y++ {
+				row := src.Pix[y*src.Stride+x0*4 : y*src.Stride+x1*4]
+				for p := 0; p < len(row); p += 4 {
+					sum[0] += int(row[p])
+					sum[1] += int(row[p+1])
+					sum[2] += int(row[p+2])
+					sum[3] += int(row[p+3])
+				}
+			}
+			count := (y1 - y0) * (x1 - x0)
+			offset := dst.PixOffset(rect.Min.X+dx, rect.Min.Y+dy)
+			for c := range sum {
+				dst.Pix[offset+c] = uint8(sum[c] / count)
+			}
+		}
+	}
+}
+
+// AppendThumbnailQuery adds query to the image URI of every cue in a WebVTT
+// thumbnails track, ahead of its #xywh fragment, so a signed query reaches
+// the sprites
+func AppendThumbnailQuery(vtt []byte, query string) []byte {
+	if query == "" {
+		return vtt
+	}
+
+	lines := strings.Split(string(vtt), "\n")
+	inCue := false
+	for i, line := range lines {
+		switch {
+		case strings.Contains(line, "-->"):
+			inCue = true
+		case strings.TrimSpace(line) == "":
+			inCue = false
+		case inCue:
+			uri, fragment, found := strings.Cut(line, "#")
+			if found {
+				fragment = "#" + fragment
+			}
+			lines[i] = withQuery(uri, query) + fragment
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
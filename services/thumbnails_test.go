@@ -0,0 +1,144 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFrames writes count 32×18 PNG frames, each a different shade of grey
+func writeFrames(t *testing.T, count int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := range count {
+		img := image.NewRGBA(image.Rect(0, 0, 32, 18))
+		shade := uint8(i * 30)
+		for p := 0; p < len(img.Pix); p += 4 {
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = shade, shade, shade, 255
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("frame-%05d.png", i+1)), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frames, err := ListFrames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frames
+}
+
+func TestGenerateSpritesLayout(t *testing.T) {
+	layout := SpriteLayout{Interval: 10 * time.Second, TileWidth: 16, Columns: 2, Rows: 2}
+
+	tests := []struct {
+		frames int
+		// sheets are the width and height of each sheet
+		sheets [][2]int
+	}{
+		{1, [][2]int{{16, 9}}},
+		{4, [][2]int{{32, 18}}},
+		{5, [][2]int{{32, 18}, {16, 9}}},
+		{7, [][2]int{{32, 18}, {32, 18}}},
+	}
+
+	for _, tt := range tests {
+		sprites, err := GenerateSprites(writeFrames(t, tt.frames), layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sprites.FrameCount != tt.frames || sprites.TileHeight != 9 {
+			t.Errorf("%d frames: FrameCount %d, TileHeight %d; want %d, 9", tt.frames, sprites.FrameCount, sprites.TileHeight, tt.frames)
+		}
+		if len(sprites.Sheets) != len(tt.sheets) {
+			t.Fatalf("%d frames: %d sheets, want %d", tt.frames, len(sprites.Sheets), len(tt.sheets))
+		}
+		for i, sheet := range sprites.Sheets {
+			if sheet.Name != SpriteName(i) {
+				t.Errorf("%d frames: sheet %d named %q", tt.frames, i, sheet.Name)
+			}
+			img, err := jpeg.Decode(bytes.NewReader(sheet.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := [2]int{img.Bounds().Dx(), img.Bounds().Dy()}; size != tt.sheets[i] {
+				t.Errorf("%d frames: sheet %d is %v, want %v", tt.frames, i, size, tt.sheets[i])
+			}
+		}
+	}
+}
+
+func TestGenerateSpritesPartialSheetCues(t *testing.T) {
+	layout := SpriteLayout{Interval: 10 * time.Second, TileWidth: 16, Columns: 2, Rows: 2}
+	sprites, err := GenerateSprites(writeFrames(t, 7), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:10.000\nsprite-000.jpg#xywh=0,0,16,9\n" +
+		"\n00:00:10.000 --> 00:00:20.000\nsprite-000.jpg#xywh=16,0,16,9\n" +
+		"\n00:00:20.000 --> 00:00:30.000\nsprite-000.jpg#xywh=0,9,16,9\n" +
+		"\n00:00:30.000 --> 00:00:40.000\nsprite-000.jpg#xywh=16,9,16,9\n" +
+		"\n00:00:40.000 --> 00:00:50.000\nsprite-001.jpg#xywh=0,0,16,9\n" +
+		"\n00:00:50.000 --> 00:01:00.000\nsprite-001.jpg#xywh=16,0,16,9\n" +
+		"\n00:01:00.000 --> 00:01:10.000\nsprite-001.jpg#xywh=0,9,16,9\n"
+	if string(sprites.VTT) != want {
+		t.Errorf("vtt = %q, want %q", sprites.VTT, want)
+	}
+
+	// Each cue's tile holds its frame's shade; the unused tile stays black
+	img, err := jpeg.Decode(bytes.NewReader(sprites.Sheets[1].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tile := range []struct {
+		x, y  int
+		shade uint8
+	}{{0, 0, 120}, {16, 0, 150}, {0, 9, 180}, {16, 9, 0}} {
+		grey := color.GrayModel.Convert(img.At(tile.x+8, tile.y+4)).(color.Gray).Y
+		if diff := int(grey) - int(tile.shade); diff < -8 || diff > 8 {
+			t.Errorf("tile at %d,%d has shade %d, want about %d", tile.x, tile.y, grey, tile.shade)
+		}
+	}
+}
+
+func TestGenerateSpritesInvalid(t *testing.T) {
+	frames := writeFrames(t, 1)
+	if _, err := GenerateSprites(nil, SpriteLayout{Interval: time.Second, TileWidth: 16, Columns: 1, Rows: 1}); err == nil {
+		t.Error("packing no frames succeeded")
+	}
+	if _, err := GenerateSprites(frames, SpriteLayout{Interval: time.Second, TileWidth: 16, Columns: 0, Rows: 1}); err == nil {
+		t.Error("a layout without columns succeeded")
+	}
+}
+
+func TestAppendThumbnailQuery(t *testing.T) {
+	vtt := "WEBVTT\n\n00:00:00.000 --> 00:00:10.000\nsprite-000.jpg#xywh=0,0,16,9\n\n" +
+		"00:00:10.000 --> 00:00:20.000\nsprite-000.jpg?v=2#xywh=16,0,16,9\n\nNOTE sprite-000.jpg\n"
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", vtt},
+		{"sid=1&sig=x", "WEBVTT\n\n00:00:00.000 --> 00:00:10.000\nsprite-000.jpg?sid=1&sig=x#xywh=0,0,16,9\n\n" +
+			"00:00:10.000 --> 00:00:20.000\nsprite-000.jpg?v=2&sid=1&sig=x#xywh=16,0,16,9\n\nNOTE sprite-000.jpg\n"},
+	}
+
+	for _, tt := range tests {
+		if got := string(AppendThumbnailQuery([]byte(vtt), tt.query)); got != tt.want {
+			t.Errorf("AppendThumbnailQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 )
 
 // TMDB Configuration
@@ -104,6 +105,17 @@ type TMDBTVDetails struct {
 	InProduction     bool        `json:"in_production"`
 }
 
+// TMDBEpisodeDetails represents one episode of a TV show from TMDB
+type TMDBEpisodeDetails struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Overview      string `json:"overview"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	AirDate       string `json:"air_date"`
+	Runtime       int    `json:"runtime"`
+}
+
 // TMDBSearchResponse represents a paginated search response
 type TMDBSearchResponse[T any] struct {
 	Page         int `json:"page"`
@@ -228,6 +240,21 @@ func (s *TMDBService) GetTVDetails(tmdbID int) (*TMDBTVDetails, error) {
 	return &result, nil
 }
 
+// GetEpisodeDetails gets detailed information about one episode of a TV show
+func (s *TMDBService) GetEpisodeDetails(tmdbID, season, episode int) (*TMDBEpisodeDetails, error) {
+	body, err := s.makeRequest(fmt.Sprintf("/tv/%d/season/%d/episode/%d", tmdbID, season, episode), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result TMDBEpisodeDetails
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // GetFullPosterURL constructs full poster URL from path
 func (s *TMDBService) GetFullPosterURL(posterPath string, size string) string {
 	if posterPath == "" {
@@ -249,3 +276,29 @@ func (s *TMDBService) GetFullBackdropURL(backdropPath string, size string) strin
 	}
 	return fmt.Sprintf("%s/%s%s", s.config.ImageBaseURL, size, backdropPath)
 }
+
+// maxImageSize caps how much of an image FetchImage reads
+const maxImageSize = 20 << 20
+
+// FetchImage downloads an image from the TMDB image server, e.g. size "w500"
+// and imagePath "/kqjL17yufvn9OVLyXYpvtyrFfak.jpg"
+func (s *TMDBService) FetchImage(size, imagePath string) ([]byte, error) {
+	resp, err := s.client.Get(s.config.ImageBaseURL + "/" + url.PathEscape(size) + "/" + url.PathEscape(strings.TrimPrefix(imagePath, "/")))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TMDB image error: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("TMDB image %s is too large", imagePath)
+	}
+	return data, nil
+}
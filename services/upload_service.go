@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultMaxUploadSize  = 50 << 30
+	defaultUploadPartsDir = ".uploads"
+	uploadExpiry          = 24 * time.Hour
+	// UploadChunkTimeout bounds how long one chunk may take to arrive.
+	// The upload stays locked to that request in the meantime.
+	UploadChunkTimeout = time.Hour
+)
+
+var (
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrUploadTooLarge         = errors.New("the upload is larger than the size limit")
+	ErrUploadOverflow         = errors.New("the chunk goes past the end of the upload")
+	ErrUploadOffsetMismatch   = errors.New("Upload-Offset does not match the upload's offset")
+	ErrUploadBusy             = errors.New("another chunk is being written to this upload")
+	ErrUploadCompleted        = errors.New("the upload is already complete")
+	ErrUploadExpired          = errors.New("the upload has expired")
+	ErrUploadChecksumMismatch = errors.New("the chunk does not match its checksum")
+	ErrUnsupportedChecksum    = errors.New("Upload-Checksum must be md5, sha1 or sha256 followed by a base64 digest")
+)
+
+// checksumAlgorithms are the Upload-Checksum algorithms chunks can be sent with
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// ChecksumAlgorithms lists the supported Upload-Checksum algorithms
+const ChecksumAlgorithms = "md5,sha1,sha256"
+
+// UploadService receives resumable uploads chunk by chunk. Received bytes
+// are kept in a local part file until the upload is complete, then moved
+// into storage and attached to the media entry.
+type UploadService struct {
+	uploads  *mongo.Collection
+	media    *mongo.Collection
+	storage  Storage
+	partsDir string
+	maxSize  int64
+}
+
+// NewUploadService creates an upload service. The size limit is read from
+// UPLOAD_MAX_BYTES and unfinished uploads are kept in UPLOAD_PARTS_DIR.
+func NewUploadService(uploads, media *mongo.Collection, storage Storage) *UploadService {
+	maxSize := int64(defaultMaxUploadSize)
+	if value, err := strconv.ParseInt(os.Getenv("UPLOAD_MAX_BYTES"), 10, 64); err == nil && value > 0 {
+		maxSize = value
+	}
+	partsDir := defaultUploadPartsDir
+	if dir := os.Getenv("UPLOAD_PARTS_DIR"); dir != "" {
+		partsDir = dir
+	}
+
+	return &UploadService{
+		uploads:  uploads,
+		media:    media,
+		storage:  storage,
+		partsDir: partsDir,
+		maxSize:  maxSize,
+	}
+}
+
+// MaxSize is the largest upload accepted, in bytes
+func (s *UploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *UploadService) partPath(uploadID string) string {
+	return filepath.Join(s.partsDir, uploadID+".part")
+}
+
+// Create starts an upload of size bytes for a media entry
+func (s *UploadService) Create(ctx context.Context, tmdbID int, filename string, size int64, createdBy string) (*models.Upload, error) {
+	if size > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	now := time.Now()
+	uploadID := bson.NewObjectID().Hex()
+	upload := &models.Upload{
+		UploadID:   uploadID,
+		TMDBID:     tmdbID,
+		Filename:   filename,
+		Size:       size,
+		Status:     models.UploadInProgress,
+		StorageKey: path.Join("uploads", strconv.Itoa(tmdbID), uploadID+strings.ToLower(path.Ext(filename))),
+		CreatedBy:  createdBy,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ExpiresAt:  now.Add(uploadExpiry),
+	}
+
+	if err := os.MkdirAll(s.partsDir, 0o755); err != nil {
+		return nil, err
+	}
+	part, err := os.Create(s.partPath(uploadID))
+	if err != nil {
+		return nil, err
+	}
+	part.Close()
+
+	if _, err = s.uploads.InsertOne(ctx, upload); err != nil {
+		os.Remove(s.partPath(uploadID))
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Get returns an upload
+func (s *UploadService) Get(ctx context.Context, uploadID string) (*models.Upload, error) {
+	var upload models.Upload
+	err := s.uploads.FindOne(ctx, bson.M{"upload_id": uploadID}).Decode(&upload)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// WriteChunk appends body to the upload, which must have received exactly
+// offset bytes so far. With a checksum ("sha256 <base64 digest>") the whole
+// chunk is discarded unless it matches; without one, whatever arrived before
+// a disconnect is kept so the client can resume from there. The upload is
+// finished once its last byte arrives; an empty chunk at the end retries a
+// finish that failed.
+func (s *UploadService) WriteChunk(ctx context.Context, uploadID string, offset int64, body io.Reader, checksum string) (*models.Upload, error) {
+	var digest hash.Hash
+	var expected []byte
+	if checksum != "" {
+		algorithm, encoded, _ := strings.Cut(checksum, " ")
+		newHash, ok := checksumAlgorithms[algorithm]
+		if !ok {
+			return nil, ErrUnsupportedChecksum
+		}
+		var err error
+		if expected, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+			return nil, ErrUnsupportedChecksum
+		}
+		digest = newHash()
+	}
+
+	upload, err := s.lock(ctx, uploadID, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	written, writeErr := s.writePart(uploadID, offset, upload.Size-offset, body, digest)
+	if writeErr == nil && digest != nil && string(digest.Sum(nil)) != string(expected) {
+		writeErr = ErrUploadChecksumMismatch
+	}
+	if writeErr != nil && (digest != nil || errors.Is(writeErr, ErrUploadOverflow)) {
+		written = 0
+	}
+	if written > 0 || writeErr != nil {
+		// Anything past the recorded offset is dropped before the next write
+		if err = os.Truncate(s.partPath(uploadID), offset+written); err != nil {
+			writeErr = errors.Join(writeErr, err)
+			written = 0
+		}
+	}
+
+	now := time.Now()
+	upload.Offset = offset + written
+	upload.UpdatedAt = now
+	upload.ExpiresAt = now.Add(uploadExpiry)
+	if writeErr == nil && upload.Offset == upload.Size {
+		writeErr = s.finish(ctx, upload)
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"offset": upload.Offset, "status": upload.Status, "updated_at": now, "expires_at": upload.ExpiresAt},
+		"$unset": bson.M{"locked_until": ""},
+	}
+	if _, err = s.uploads.UpdateOne(ctx, bson.M{"upload_id": uploadID, "locked_until": upload.LockedUntil}, update); err != nil {
+		return nil, errors.Join(writeErr, err)
+	}
+	upload.LockedUntil = nil
+	return upload, writeErr
+}
+
+// lock claims an in-progress upload at offset for one chunk
+func (s *UploadService) lock(ctx context.Context, uploadID string, offset int64) (*models.Upload, error) {
+	now := time.Now()
+	filter := bson.M{
+		"upload_id":  uploadID,
+		"status":     models.UploadInProgress,
+		"offset":     offset,
+		"expires_at": bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"locked_until": bson.M{"$exists": false}},
+			bson.M{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	// Mongo keeps milliseconds, so the lock is matched on that precision
+	lockedUntil := now.Add(UploadChunkTimeout).Truncate(time.Millisecond)
+	update := bson.M{"$set": bson.M{"locked_until": lockedUntil}}
+
+	var upload models.Upload
+	err := s.uploads.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&upload)
+	if err == nil {
+		return &upload, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Work out why the upload couldn't be claimed
+	current, err := s.Get(ctx, uploadID)
+	switch {
+	case err != nil:
+		return nil, err
+	case current.Status == models.UploadCompleted:
+		return nil, ErrUploadCompleted
+	case !current.ExpiresAt.After(now):
+		return nil, ErrUploadExpired
+	case current.Offset != offset:
+		return nil, ErrUploadOffsetMismatch
+	default:
+		return nil, ErrUploadBusy
+	}
+}
+
+// writePart copies up to limit bytes of body into the part file at offset.
+// A body longer than limit fails with ErrUploadOverflow.
+func (s *UploadService) writePart(uploadID string, offset, limit int64, body io.Reader, digest hash.Hash) (int64, error) {
+	part, err := os.OpenFile(s.partPath(uploadID), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer part.Close()
+	if _, err = part.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var target io.Writer = part
+	if digest != nil {
+		target = io.MultiWriter(part, digest)
+	}
+	written, err := io.Copy(target, io.LimitReader(body, limit+1))
+	if err == nil && written > limit {
+		err = ErrUploadOverflow
+	}
+	if syncErr := part.Sync(); err == nil {
+		err = syncErr
+	}
+	return written, err
+}
+
+// finish moves a complete upload into storage, makes it the media's video
+// file and adds it to the media's sources as the default, as the video file
+// was preferred over the URL before sources
+func (s *UploadService) finish(ctx context.Context, upload *models.Upload) error {
+	// A retried finish may find the file already moved
+	partPath := s.partPath(upload.UploadID)
+	if _, err := os.Stat(partPath); err == nil {
+		if err = s.storage.PutFile(ctx, upload.StorageKey, partPath); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var media models.Media
+	if err := s.media.FindOne(ctx, bson.M{"tmdb_id": upload.TMDBID}).Decode(&media); err != nil {
+		return err
+	}
+
+	// The upload is also offered as a source, once however often this runs.
+	// Titles not yet migrated to sources keep their legacy video as well, but
+	// the upload replaces whichever source was the default.
+	source := models.VideoSource{
+		SourceID:   upload.UploadID,
+		Container:  SourceContainer(upload.Filename),
+		StorageKey: upload.StorageKey,
+		Default:    true,
+	}
+	legacy := DefaultSources(media.VideoURL, media.VideoFile)
+	existing := bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$sources", bson.M{"$literal": legacy}}},
+		"in":    bson.M{"$mergeObjects": bson.A{"$$this", bson.M{"default": false}}},
+	}}
+	update := bson.A{bson.M{"$set": bson.M{
+		"sources":    bson.M{"$concatArrays": bson.A{existing, bson.A{bson.M{"$literal": source}}}},
+		"video_file": upload.StorageKey,
+		"updated_at": time.Now(),
+	}}}
+	filter := bson.M{"tmdb_id": upload.TMDBID, "sources.source_id": bson.M{"$ne": upload.UploadID}}
+	if _, err := s.media.UpdateOne(ctx, filter, update); err != nil {
+		return err
+	}
+	upload.Status = models.UploadCompleted
+	return nil
+}
+
+// SweepExpired deletes the in-progress uploads that expired and aren't taking
+// a chunk, along with their part files. It returns how many were deleted.
+func (s *UploadService) SweepExpired(ctx context.Context) (int, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":     models.UploadInProgress,
+		"expires_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": bson.M{"$exists": false}},
+			bson.M{"locked_until": bson.M{"$lt": now}},
+		},
+	}
+	var expired []string
+	if err := s.uploads.Distinct(ctx, "upload_id", filter).Decode(&expired); err != nil {
+		return 0, err
+	}
+
+	swept := 0
+	for _, uploadID := range expired {
+		// Matching on the filter again skips uploads a client resumed since
+		result, err := s.uploads.DeleteOne(ctx, bson.M{"upload_id": uploadID, "$and": bson.A{filter}})
+		if err != nil {
+			return swept, err
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		if err = os.Remove(s.partPath(uploadID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return swept, err
+		}
+		swept++
+	}
+	return swept, nil
+}
+
+// Abort stops an unfinished upload and deletes what was received
+func (s *UploadService) Abort(ctx context.Context, uploadID string) error {
+	result, err := s.uploads.DeleteOne(ctx, bson.M{"upload_id": uploadID, "status": models.UploadInProgress})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		if _, err = s.Get(ctx, uploadID); err != nil {
+			return err
+		}
+		return ErrUploadCompleted
+	}
+
+	if err = os.Remove(s.partPath(uploadID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
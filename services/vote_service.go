@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrVoteTargetNotFound = errors.New("vote target not found")
+	ErrVoteNotFound       = errors.New("vote not found")
+)
+
+// VoteCounts are the counters of a target after a vote change
+type VoteCounts struct {
+	Up    int `json:"up"`
+	Down  int `json:"down"`
+	Score int `json:"score"`
+}
+
+// VoteService keeps one vote document per user and target, and maintains
+// counters on the target document with $inc so they never need a recount.
+type VoteService struct {
+	votes      *mongo.Collection
+	targets    *mongo.Collection
+	targetType string
+	idField    string
+	upField    string
+	downField  string
+	scoreField string
+}
+
+// NewVoteService creates a vote service for one kind of target. downField and
+// scoreField may be empty for targets that only take likes.
+func NewVoteService(votes, targets *mongo.Collection, targetType, idField, upField, downField, scoreField string) *VoteService {
+	return &VoteService{
+		votes:      votes,
+		targets:    targets,
+		targetType: targetType,
+		idField:    idField,
+		upField:    upField,
+		downField:  downField,
+		scoreField: scoreField,
+	}
+}
+
+// Cast records the user's vote, replacing any earlier vote on the same target.
+// It also returns the earlier vote's value, 0 when there was none.
+func (s *VoteService) Cast(ctx context.Context, targetID, userID string, value int) (*VoteCounts, int, error) {
+	now := time.Now()
+	filter := bson.M{"target_type": s.targetType, "target_id": targetID, "user_id": userID}
+	update := bson.M{
+		"$set":         bson.M{"value": value, "updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous models.Vote
+	err := s.votes.FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	if mongo.IsDuplicateKeyError(err) {
+		err = s.votes.FindOneAndUpdate(ctx, filter, update, opts).Decode(&previous)
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, 0, err
+	}
+
+	counts, err := s.applyDelta(ctx, targetID, previous.Value, value)
+	return counts, previous.Value, err
+}
+
+// Clear removes the user's vote on the target
+func (s *VoteService) Clear(ctx context.Context, targetID, userID string) (*VoteCounts, error) {
+	var previous models.Vote
+	err := s.votes.FindOneAndDelete(ctx, bson.M{"target_type": s.targetType, "target_id": targetID, "user_id": userID}).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrVoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.applyDelta(ctx, targetID, previous.Value, 0)
+}
+
+// DeleteTarget removes every vote on a target that is being deleted
+func (s *VoteService) DeleteTarget(ctx context.Context, targetID string) error {
+	_, err := s.votes.DeleteMany(ctx, bson.M{"target_type": s.targetType, "target_id": targetID})
+	return err
+}
+
+// Voters returns the users who gave a target an up vote
+func (s *VoteService) Voters(ctx context.Context, targetID string) ([]string, error) {
+	var voters []string
+	err := s.votes.Distinct(ctx, "user_id", bson.M{"target_type": s.targetType, "target_id": targetID, "value": 1}).Decode(&voters)
+	return voters, err
+}
+
+func (s *VoteService) applyDelta(ctx context.Context, targetID string, previous, current int) (*VoteCounts, error) {
+	upDelta := boolToInt(current == 1) - boolToInt(previous == 1)
+	downDelta := boolToInt(current == -1) - boolToInt(previous == -1)
+
+	inc := bson.M{s.upField: upDelta}
+	if s.downField != "" {
+		inc[s.downField] = downDelta
+	}
+	if s.scoreField != "" {
+		inc[s.scoreField] = upDelta - downDelta
+	}
+
+	projection := bson.M{s.upField: 1}
+	if s.downField != "" {
+		projection[s.downField] = 1
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(projection)
+
+	var target bson.M
+	err := s.targets.FindOneAndUpdate(ctx, bson.M{s.idField: targetID}, bson.M{"$inc": inc}, opts).Decode(&target)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrVoteTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts := &VoteCounts{Up: intField(target, s.upField)}
+	if s.downField != "" {
+		counts.Down = intField(target, s.downField)
+	}
+	counts.Score = counts.Up - counts.Down
+
+	return counts, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func intField(doc bson.M, field string) int {
+	switch v := doc[field].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
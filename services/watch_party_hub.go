@@ -0,0 +1,432 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// partyOutboxSize is how many messages may queue for a member before it is dropped
+	partyOutboxSize = 32
+	// maxPartyTransit caps the transit time credited to a playback command
+	maxPartyTransit = 5 * time.Second
+	// maxPartyChatLength is the longest chat message in characters
+	maxPartyChatLength = 500
+)
+
+// Watch party message types. Clients send ping, sync, play, pause, seek, chat
+// and transfer_host; the server sends welcome, pong, state, presence, chat
+// and error.
+const (
+	PartyPing         = "ping"
+	PartyPong         = "pong"
+	PartySync         = "sync"
+	PartyPlay         = "play"
+	PartyPause        = "pause"
+	PartySeek         = "seek"
+	PartyChat         = "chat"
+	PartyTransferHost = "transfer_host"
+	PartyWelcome      = "welcome"
+	PartyState        = "state"
+	PartyPresence     = "presence"
+	PartyError        = "error"
+)
+
+// PartyMessage is the JSON frame exchanged over a watch party socket.
+//
+// Times are Unix milliseconds. Clients estimate their clock offset from
+// ping/pong (offset = server_time - client_time - rtt/2) and send sent_at in
+// server time with playback commands, so the server can credit the time the
+// command spent in transit. State frames carry the position at server_time;
+// clients add the time elapsed since then while playing.
+type PartyMessage struct {
+	Type         string         `json:"type"`
+	UserID       string         `json:"user_id,omitempty"`
+	UserName     string         `json:"user_name,omitempty"`
+	Position     *float64       `json:"position,omitempty"`
+	SentAt       int64          `json:"sent_at,omitempty"`
+	ClientTime   int64          `json:"client_time,omitempty"`
+	ServerTime   int64          `json:"server_time,omitempty"`
+	Text         string         `json:"text,omitempty"`
+	TargetUserID string         `json:"target_user_id,omitempty"`
+	HostID       string         `json:"host_id,omitempty"`
+	State        *PlaybackState `json:"state,omitempty"`
+	Members      []PartyMember  `json:"members,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// PlaybackState is the authoritative playback position of a room
+type PlaybackState struct {
+	Playing    bool    `json:"playing"`
+	Position   float64 `json:"position"`
+	ServerTime int64   `json:"server_time"`
+}
+
+// PartyMember is one user present in a room
+type PartyMember struct {
+	UserID   string    `json:"user_id"`
+	UserName string    `json:"user_name"`
+	IsHost   bool      `json:"is_host"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// PartySnapshot is what the hub reports when a room's host or playback
+// changes hands or the last member leaves, so it can be persisted
+type PartySnapshot struct {
+	PartyID  string
+	HostID   string
+	Playing  bool
+	Position float64
+}
+
+// WatchPartyHub keeps the live state of every active watch party. It knows
+// nothing about the transport: a connection is a channel of outgoing
+// messages plus Handle for incoming ones.
+type WatchPartyHub struct {
+	mu       sync.Mutex
+	rooms    map[string]*partyRoom
+	nextID   uint64
+	onChange func(PartySnapshot)
+	now      func() time.Time
+}
+
+// NewWatchPartyHub creates a hub. onChange, if set, is called outside any
+// lock whenever a room's host changes, the host plays, pauses or seeks, or
+// its last member leaves.
+func NewWatchPartyHub(onChange func(PartySnapshot)) *WatchPartyHub {
+	return &WatchPartyHub{
+		rooms:    map[string]*partyRoom{},
+		onChange: onChange,
+		now:      time.Now,
+	}
+}
+
+type partyRoom struct {
+	mu        sync.Mutex
+	partyID   string
+	hostID    string
+	playing   bool
+	position  float64
+	updatedAt time.Time
+	members   map[uint64]*PartyConnection
+}
+
+// PartyConnection is one socket's membership in a room
+type PartyConnection struct {
+	hub      *WatchPartyHub
+	room     *partyRoom
+	id       uint64
+	userID   string
+	userName string
+	canChat  bool
+	joinedAt time.Time
+	outbox   chan PartyMessage
+	closed   bool
+}
+
+// Outbox delivers the messages for this connection. It is closed when the
+// connection leaves or falls too far behind.
+func (c *PartyConnection) Outbox() <-chan PartyMessage {
+	return c.outbox
+}
+
+// Join adds a user to a room, creating it from the saved snapshot when it
+// isn't live yet. When the host isn't connected the longest present member
+// takes over, as on Leave. The new member gets a welcome frame and everyone
+// else a presence update. Members who can't chat, such as banned users, still watch.
+func (h *WatchPartyHub) Join(saved PartySnapshot, userID, userName string, canChat bool) *PartyConnection {
+	h.mu.Lock()
+	room, ok := h.rooms[saved.PartyID]
+	if !ok {
+		room = &partyRoom{
+			partyID:   saved.PartyID,
+			hostID:    saved.HostID,
+			playing:   saved.Playing,
+			position:  saved.Position,
+			updatedAt: h.now(),
+			members:   map[uint64]*PartyConnection{},
+		}
+		h.rooms[saved.PartyID] = room
+	}
+	h.nextID++
+	conn := &PartyConnection{
+		hub:      h,
+		room:     room,
+		id:       h.nextID,
+		userID:   userID,
+		userName: userName,
+		canChat:  canChat,
+		joinedAt: h.now(),
+		outbox:   make(chan PartyMessage, partyOutboxSize),
+	}
+	room.mu.Lock()
+	h.mu.Unlock()
+
+	room.members[conn.id] = conn
+	now := h.now()
+
+	// A room rebuilt from a snapshot may name a host who isn't back yet
+	var snapshot *PartySnapshot
+	if !room.presentLocked(room.hostID) {
+		room.hostID = room.earliestMemberLocked()
+		s := room.snapshotLocked(now)
+		snapshot = &s
+	}
+
+	state := room.stateAt(now)
+	conn.sendLocked(PartyMessage{
+		Type:       PartyWelcome,
+		UserID:     userID,
+		HostID:     room.hostID,
+		State:      &state,
+		Members:    room.presenceLocked(),
+		ServerTime: now.UnixMilli(),
+	})
+	room.broadcastPresenceLocked(now)
+	room.mu.Unlock()
+
+	if snapshot != nil && h.onChange != nil {
+		h.onChange(*snapshot)
+	}
+	return conn
+}
+
+// Leave removes the connection. When the host's last connection leaves, the
+// longest present member becomes host.
+func (c *PartyConnection) Leave() {
+	h, room := c.hub, c.room
+
+	h.mu.Lock()
+	room.mu.Lock()
+	c.closeLocked()
+
+	var snapshot *PartySnapshot
+	now := h.now()
+	if len(room.members) == 0 {
+		if h.rooms[room.partyID] == room {
+			delete(h.rooms, room.partyID)
+		}
+		s := room.snapshotLocked(now)
+		snapshot = &s
+	} else {
+		if !room.presentLocked(room.hostID) {
+			room.hostID = room.earliestMemberLocked()
+			s := room.snapshotLocked(now)
+			snapshot = &s
+		}
+		room.broadcastPresenceLocked(now)
+	}
+	room.mu.Unlock()
+	h.mu.Unlock()
+
+	if snapshot != nil && h.onChange != nil {
+		h.onChange(*snapshot)
+	}
+}
+
+// Handle applies a message received from this connection
+func (c *PartyConnection) Handle(msg PartyMessage) {
+	room := c.room
+	room.mu.Lock()
+
+	now := c.hub.now()
+	var snapshot *PartySnapshot
+
+	switch msg.Type {
+	case PartyPing:
+		c.sendLocked(PartyMessage{Type: PartyPong, ClientTime: msg.ClientTime, ServerTime: now.UnixMilli()})
+
+	case PartySync:
+		state := room.stateAt(now)
+		c.sendLocked(PartyMessage{Type: PartyState, HostID: room.hostID, State: &state, ServerTime: now.UnixMilli()})
+
+	case PartyPlay, PartyPause, PartySeek:
+		if c.userID != room.hostID {
+			c.sendLocked(partyError("only the host can control playback"))
+			break
+		}
+		if msg.Type == PartySeek && (msg.Position == nil || *msg.Position < 0) {
+			c.sendLocked(partyError("seek needs a position"))
+			break
+		}
+		room.applyLocked(msg, now)
+		state := room.stateAt(now)
+		room.broadcastLocked(PartyMessage{Type: PartyState, UserID: c.userID, HostID: room.hostID, State: &state, ServerTime: now.UnixMilli()})
+		s := room.snapshotLocked(now)
+		snapshot = &s
+
+	case PartyChat:
+		if !c.canChat {
+			c.sendLocked(partyError("your account has been banned from posting"))
+			break
+		}
+		text := strings.TrimSpace(msg.Text)
+		if text == "" || utf8.RuneCountInString(text) > maxPartyChatLength {
+			c.sendLocked(partyError("chat messages must be 1 to 500 characters"))
+			break
+		}
+		room.broadcastLocked(PartyMessage{Type: PartyChat, UserID: c.userID, UserName: c.userName, Text: text, ServerTime: now.UnixMilli()})
+
+	case PartyTransferHost:
+		if c.userID != room.hostID {
+			c.sendLocked(partyError("only the host can hand over the room"))
+			break
+		}
+		if !room.presentLocked(msg.TargetUserID) {
+			c.sendLocked(partyError("the new host must be in the room"))
+			break
+		}
+		room.hostID = msg.TargetUserID
+		s := room.snapshotLocked(now)
+		snapshot = &s
+		room.broadcastPresenceLocked(now)
+
+	default:
+		c.sendLocked(partyError("unknown message type"))
+	}
+
+	room.mu.Unlock()
+
+	if snapshot != nil && c.hub.onChange != nil {
+		c.hub.onChange(*snapshot)
+	}
+}
+
+// Members lists the users in a live room
+func (h *WatchPartyHub) Members(partyID string) []PartyMember {
+	h.mu.Lock()
+	room, ok := h.rooms[partyID]
+	h.mu.Unlock()
+	if !ok {
+		return []PartyMember{}
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	return room.presenceLocked()
+}
+
+func partyError(message string) PartyMessage {
+	return PartyMessage{Type: PartyError, Error: message}
+}
+
+// applyLocked updates playback from a host command. While playing, the time
+// the command spent in transit is added so everyone lands where the host is.
+func (r *partyRoom) applyLocked(msg PartyMessage, now time.Time) {
+	transit := time.Duration(0)
+	if msg.SentAt > 0 {
+		transit = min(max(now.Sub(time.UnixMilli(msg.SentAt)), 0), maxPartyTransit)
+	}
+
+	position := r.stateAt(now).Position
+	if msg.Position != nil && *msg.Position >= 0 {
+		position = *msg.Position
+	}
+
+	switch msg.Type {
+	case PartyPlay:
+		r.playing = true
+	case PartyPause:
+		r.playing = false
+	}
+	if r.playing {
+		position += transit.Seconds()
+	}
+
+	r.position = position
+	r.updatedAt = now
+}
+
+func (r *partyRoom) stateAt(now time.Time) PlaybackState {
+	position := r.position
+	if r.playing {
+		position += now.Sub(r.updatedAt).Seconds()
+	}
+	return PlaybackState{Playing: r.playing, Position: position, ServerTime: now.UnixMilli()}
+}
+
+func (r *partyRoom) snapshotLocked(now time.Time) PartySnapshot {
+	state := r.stateAt(now)
+	return PartySnapshot{PartyID: r.partyID, HostID: r.hostID, Playing: state.Playing, Position: state.Position}
+}
+
+// presenceLocked lists each user once, however many connections they have open
+func (r *partyRoom) presenceLocked() []PartyMember {
+	byUser := map[string]PartyMember{}
+	for _, member := range r.members {
+		existing, ok := byUser[member.userID]
+		if ok && existing.JoinedAt.Before(member.joinedAt) {
+			continue
+		}
+		byUser[member.userID] = PartyMember{
+			UserID:   member.userID,
+			UserName: member.userName,
+			IsHost:   member.userID == r.hostID,
+			JoinedAt: member.joinedAt,
+		}
+	}
+
+	members := make([]PartyMember, 0, len(byUser))
+	for _, member := range byUser {
+		members = append(members, member)
+	}
+	// Oldest first so the order is stable for clients
+	slices.SortFunc(members, func(a, b PartyMember) int {
+		return a.JoinedAt.Compare(b.JoinedAt)
+	})
+	return members
+}
+
+func (r *partyRoom) presentLocked(userID string) bool {
+	for _, member := range r.members {
+		if member.userID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *partyRoom) earliestMemberLocked() string {
+	var earliest *PartyConnection
+	for _, member := range r.members {
+		if earliest == nil || member.joinedAt.Before(earliest.joinedAt) {
+			earliest = member
+		}
+	}
+	return earliest.userID
+}
+
+func (r *partyRoom) broadcastPresenceLocked(now time.Time) {
+	r.broadcastLocked(PartyMessage{Type: PartyPresence, HostID: r.hostID, Members: r.presenceLocked(), ServerTime: now.UnixMilli()})
+}
+
+func (r *partyRoom) broadcastLocked(msg PartyMessage) {
+	for _, member := range r.members {
+		member.sendLocked(msg)
+	}
+}
+
+// sendLocked queues a message without blocking; a member whose queue is full
+// is disconnected and can rejoin to get a fresh welcome
+func (c *PartyConnection) sendLocked(msg PartyMessage) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.outbox <- msg:
+	default:
+		c.closeLocked()
+	}
+}
+
+func (c *PartyConnection) closeLocked() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(c.room.members, c.id)
+	close(c.outbox)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// RelayWatchParty pumps JSON frames between a socket and its hub connection
+// until either side goes away, then leaves the room
+func RelayWatchParty(ws *websocket.Conn, conn *PartyConnection) {
+	defer conn.Leave()
+
+	go func() {
+		for msg := range conn.Outbox() {
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				break
+			}
+		}
+		// Closing the socket ends the read loop below
+		ws.Close()
+	}()
+
+	for {
+		var msg PartyMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		conn.Handle(msg)
+	}
+}
+
+// WebSocketOriginCheck returns a handshake that accepts only the origins in
+// allowed (comma separated, such as "https://example.com"). With allowed
+// empty every socket is refused.
+func WebSocketOriginCheck(allowed string) func(*websocket.Config, *http.Request) error {
+	return func(config *websocket.Config, r *http.Request) error {
+		if allowed == "" {
+			return websocket.ErrBadWebSocketOrigin
+		}
+
+		origin, err := url.Parse(r.Header.Get("Origin"))
+		if err != nil || origin.Host == "" {
+			return websocket.ErrBadWebSocketOrigin
+		}
+		for _, candidate := range strings.Split(allowed, ",") {
+			if strings.EqualFold(strings.TrimSpace(candidate), origin.Scheme+"://"+origin.Host) {
+				config.Origin = origin
+				return nil
+			}
+		}
+		return websocket.ErrBadWebSocketOrigin
+	}
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	testPartyID     = "party-1"
+	testPartyOrigin = "http://party.test"
+)
+
+// memoryTicketStore is an in-memory WatchPartyTicketStore
+type memoryTicketStore struct {
+	mu      sync.Mutex
+	tickets map[string]models.WatchPartyTicket
+}
+
+func (s *memoryTicketStore) Insert(_ context.Context, ticket models.WatchPartyTicket) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets[ticket.TicketHash] = ticket
+	return nil
+}
+
+func (s *memoryTicketStore) Take(_ context.Context, ticketHash, partyID string, now time.Time) (models.WatchPartyTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ticket, ok := s.tickets[ticketHash]
+	if !ok || ticket.PartyID != partyID || !ticket.ExpiresAt.After(now) {
+		return models.WatchPartyTicket{}, ErrInvalidTicket
+	}
+	delete(s.tickets, ticketHash)
+	return ticket, nil
+}
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// partyServer serves one party over WebSockets the way the controller does:
+// the handshake checks the origin, then redeems the ticket
+type partyServer struct {
+	hub     *WatchPartyHub
+	tickets *WatchPartyTicketService
+	clock   *fakeClock
+	banned  map[string]bool
+	wsURL   string
+}
+
+func newPartyServer(t *testing.T, saved PartySnapshot) *partyServer {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)}
+	p := &partyServer{
+		hub:     NewWatchPartyHub(nil),
+		tickets: NewWatchPartyTicketService(&memoryTicketStore{tickets: map[string]models.WatchPartyTicket{}}),
+		clock:   clock,
+		banned:  map[string]bool{},
+	}
+	p.hub.now = clock.now
+	p.tickets.now = clock.now
+
+	checkOrigin := WebSocketOriginCheck(testPartyOrigin)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var userID string
+		websocket.Server{
+			Handshake: func(config *websocket.Config, r *http.Request) error {
+				if err := checkOrigin(config, r); err != nil {
+					return err
+				}
+				var err error
+				userID, err = p.tickets.Redeem(r.Context(), saved.PartyID, r.URL.Query().Get("ticket"))
+				return err
+			},
+			Handler: func(ws *websocket.Conn) {
+				RelayWatchParty(ws, p.hub.Join(saved, userID, "User "+userID, !p.banned[userID]))
+			},
+		}.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	p.wsURL = "ws" + strings.TrimPrefix(server.URL, "http")
+	return p
+}
+
+func (p *partyServer) ticket(t *testing.T, userID string) string {
+	t.Helper()
+	ticket, _, err := p.tickets.Issue(context.Background(), testPartyID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+func (p *partyServer) dial(ticket, origin string) (*websocket.Conn, error) {
+	return websocket.Dial(p.wsURL+"/?ticket="+ticket, "", origin)
+}
+
+// connect joins the party as userID a second after the previous member, and
+// returns the client once its welcome arrived
+func (p *partyServer) connect(t *testing.T, userID string) (*partyClient, PartyMessage) {
+	t.Helper()
+	p.clock.advance(time.Second)
+	ws, err := p.dial(p.ticket(t, userID), testPartyOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &partyClient{t: t, ws: ws}
+	t.Cleanup(client.close)
+	return client, client.expect(PartyWelcome)
+}
+
+type partyClient struct {
+	t  *testing.T
+	ws *websocket.Conn
+}
+
+func (c *partyClient) send(msg PartyMessage) {
+	c.t.Helper()
+	if err := websocket.JSON.Send(c.ws, msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads frames until one of type kind arrives
+func (c *partyClient) expect(kind string) PartyMessage {
+	c.t.Helper()
+	c.ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg PartyMessage
+		if err := websocket.JSON.Receive(c.ws, &msg); err != nil {
+			c.t.Fatalf("waiting for %s: %v", kind, err)
+		}
+		if msg.Type == kind {
+			return msg
+		}
+	}
+}
+
+// expectPresence reads presence frames until one lists count users
+func (c *partyClient) expectPresence(count int) PartyMessage {
+	c.t.Helper()
+	for {
+		if msg := c.expect(PartyPresence); len(msg.Members) == count {
+			return msg
+		}
+	}
+}
+
+func (c *partyClient) close() {
+	c.ws.Close()
+}
+
+func position(v float64) *float64 {
+	return &v
+}
+
+func assertPosition(t *testing.T, state *PlaybackState, playing bool, want float64) {
+	t.Helper()
+	if state == nil {
+		t.Fatal("frame has no state")
+	}
+	if state.Playing != playing || math.Abs(state.Position-want) > 0.001 {
+		t.Errorf("state = playing %v at %.3f, want playing %v at %.3f", state.Playing, state.Position, playing, want)
+	}
+}
+
+func TestWatchPartyTicketSingleUse(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+
+	ticket := p.ticket(t, "host")
+	ws, err := p.dial(ticket, testPartyOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.Close()
+
+	if _, err := p.dial(ticket, testPartyOrigin); err == nil {
+		t.Error("a used ticket opened a second socket")
+	}
+	if _, err := p.dial("", testPartyOrigin); err == nil {
+		t.Error("a socket opened without a ticket")
+	}
+
+	other := p.ticket(t, "guest")
+	if _, err := p.tickets.Redeem(context.Background(), "party-2", other); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("Redeem for another party = %v, want ErrInvalidTicket", err)
+	}
+
+	expired := p.ticket(t, "guest")
+	p.clock.advance(watchPartyTicketTTL + time.Second)
+	if _, err := p.dial(expired, testPartyOrigin); err == nil {
+		t.Error("an expired ticket opened a socket")
+	}
+}
+
+func TestWatchPartyOriginRejected(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+
+	ticket := p.ticket(t, "host")
+	if _, err := p.dial(ticket, "http://evil.test"); err == nil {
+		t.Error("a socket from another origin was accepted")
+	}
+	// The refused handshake left the ticket unused
+	ws, err := p.dial(ticket, testPartyOrigin)
+	if err != nil {
+		t.Fatalf("the ticket was used up by a refused handshake: %v", err)
+	}
+	ws.Close()
+
+	check := WebSocketOriginCheck("")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", testPartyOrigin)
+	if err := check(&websocket.Config{}, r); err == nil {
+		t.Error("an empty allow list accepted a socket")
+	}
+}
+
+func TestWatchPartyPlaybackDrift(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host", Position: 10})
+
+	host, welcome := p.connect(t, "host")
+	assertPosition(t, welcome.State, false, 10)
+	guest, _ := p.connect(t, "guest")
+
+	// The play command spent 200ms in transit, which is credited
+	sentAt := p.clock.now().Add(-200 * time.Millisecond).UnixMilli()
+	host.send(PartyMessage{Type: PartyPlay, SentAt: sentAt})
+	assertPosition(t, guest.expect(PartyState).State, true, 10.2)
+
+	p.clock.advance(5 * time.Second)
+	guest.send(PartyMessage{Type: PartySync})
+	assertPosition(t, guest.expect(PartyState).State, true, 15.2)
+
+	host.send(PartyMessage{Type: PartySeek, Position: position(60)})
+	assertPosition(t, guest.expect(PartyState).State, true, 60)
+
+	// Transit is capped, so a stale timestamp can't jump playback ahead
+	host.send(PartyMessage{Type: PartySeek, Position: position(60), SentAt: p.clock.now().Add(-time.Minute).UnixMilli()})
+	assertPosition(t, guest.expect(PartyState).State, true, 60+maxPartyTransit.Seconds())
+
+	host.send(PartyMessage{Type: PartyPause})
+	assertPosition(t, guest.expect(PartyState).State, false, 65)
+	p.clock.advance(time.Minute)
+	guest.send(PartyMessage{Type: PartySync})
+	assertPosition(t, guest.expect(PartyState).State, false, 65)
+
+	guest.send(PartyMessage{Type: PartyPlay})
+	if msg := guest.expect(PartyError); msg.Error != "only the host can control playback" {
+		t.Errorf("guest play error = %q", msg.Error)
+	}
+	host.send(PartyMessage{Type: PartySeek})
+	host.expect(PartyError)
+}
+
+func TestWatchPartySavesPlayback(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+	saved := make(chan PartySnapshot, 10)
+	p.hub.onChange = func(snapshot PartySnapshot) { saved <- snapshot }
+
+	host, _ := p.connect(t, "host")
+	expectSaved := func(playing bool, position float64) {
+		t.Helper()
+		select {
+		case snapshot := <-saved:
+			if snapshot.Playing != playing || snapshot.Position != position {
+				t.Errorf("saved playing %v at %.1f, want playing %v at %.1f", snapshot.Playing, snapshot.Position, playing, position)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("playback change was not saved")
+		}
+	}
+
+	host.send(PartyMessage{Type: PartyPlay})
+	expectSaved(true, 0)
+	host.send(PartyMessage{Type: PartySeek, Position: position(30)})
+	expectSaved(true, 30)
+	host.send(PartyMessage{Type: PartyPause})
+	expectSaved(false, 30)
+}
+
+func TestWatchPartyHostTransfer(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+
+	host, _ := p.connect(t, "host")
+	guest, _ := p.connect(t, "guest")
+	host.expectPresence(2)
+
+	guest.send(PartyMessage{Type: PartyTransferHost, TargetUserID: "guest"})
+	guest.expect(PartyError)
+	host.send(PartyMessage{Type: PartyTransferHost, TargetUserID: "nobody"})
+	host.expect(PartyError)
+
+	host.send(PartyMessage{Type: PartyTransferHost, TargetUserID: "guest"})
+	if msg := guest.expect(PartyPresence); msg.HostID != "guest" {
+		t.Fatalf("host after transfer = %q, want guest", msg.HostID)
+	}
+	host.send(PartyMessage{Type: PartyPlay})
+	host.expect(PartyError)
+	guest.send(PartyMessage{Type: PartyPlay})
+	guest.expect(PartyState)
+
+	// When the host leaves, the longest present member takes over
+	guest.close()
+	if msg := host.expectPresence(1); msg.HostID != "host" {
+		t.Errorf("host after the host left = %q, want host", msg.HostID)
+	}
+}
+
+func TestWatchPartyRehydratedHostAbsent(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host", Position: 42})
+
+	guest, welcome := p.connect(t, "guest")
+	if welcome.HostID != "guest" {
+		t.Fatalf("host of a rebuilt room = %q, want guest", welcome.HostID)
+	}
+	guest.send(PartyMessage{Type: PartyPlay})
+	assertPosition(t, guest.expect(PartyState).State, true, 42)
+
+	// The old host comes back as a member
+	_, welcome = p.connect(t, "host")
+	if welcome.HostID != "guest" {
+		t.Errorf("host after the old host returned = %q, want guest", welcome.HostID)
+	}
+}
+
+func TestWatchPartyChat(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+	p.banned["banned"] = true
+
+	host, _ := p.connect(t, "host")
+	banned, _ := p.connect(t, "banned")
+
+	banned.send(PartyMessage{Type: PartyChat, Text: "hello"})
+	if msg := banned.expect(PartyError); msg.Error != "your account has been banned from posting" {
+		t.Errorf("banned chat error = %q", msg.Error)
+	}
+
+	host.send(PartyMessage{Type: PartyChat, Text: "  welcome  "})
+	msg := banned.expect(PartyChat)
+	if msg.UserID != "host" || msg.Text != "welcome" {
+		t.Errorf("chat = %+v, want trimmed text from host", msg)
+	}
+	if msg = host.expect(PartyChat); msg.UserID != "host" {
+		t.Errorf("the sender's own chat came from %q", msg.UserID)
+	}
+
+	host.send(PartyMessage{Type: PartyChat, Text: strings.Repeat("a", maxPartyChatLength+1)})
+	host.expect(PartyError)
+}
+
+func TestWatchPartyPresence(t *testing.T) {
+	p := newPartyServer(t, PartySnapshot{PartyID: testPartyID, HostID: "host"})
+
+	host, welcome := p.connect(t, "host")
+	if len(welcome.Members) != 1 || !welcome.Members[0].IsHost {
+		t.Fatalf("welcome members = %+v, want just the host", welcome.Members)
+	}
+
+	guest, _ := p.connect(t, "guest")
+	msg := host.expectPresence(2)
+	if msg.Members[0].UserID != "host" || msg.Members[1].UserID != "guest" {
+		t.Errorf("members = %+v, want host then guest", msg.Members)
+	}
+
+	// A second socket for the same user doesn't list them twice
+	second, welcome := p.connect(t, "guest")
+	if len(welcome.Members) != 2 {
+		t.Errorf("members with a second socket = %+v", welcome.Members)
+	}
+	host.expectPresence(2)
+	second.close()
+	host.expectPresence(2)
+
+	guest.close()
+	host.expectPresence(1)
+	if members := p.hub.Members(testPartyID); len(members) != 1 || members[0].UserID != "host" {
+		t.Errorf("Members = %+v, want just the host", members)
+	}
+
+	host.close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(p.hub.Members(testPartyID)) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the room was not closed after everyone left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
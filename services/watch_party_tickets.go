@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Har2yQn78/Stream_Platform/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// watchPartyTicketTTL is how long a client has to open its socket
+const watchPartyTicketTTL = 30 * time.Second
+
+var ErrInvalidTicket = errors.New("the ticket is invalid, expired or already used")
+
+// WatchPartyTicketStore keeps issued tickets
+type WatchPartyTicketStore interface {
+	Insert(ctx context.Context, ticket models.WatchPartyTicket) error
+	// Take removes and returns the ticket with the given hash if it is for
+	// partyID and unexpired at now, or returns ErrInvalidTicket
+	Take(ctx context.Context, ticketHash, partyID string, now time.Time) (models.WatchPartyTicket, error)
+}
+
+// MongoWatchPartyTicketStore keeps tickets in a collection with a TTL index on expires_at
+type MongoWatchPartyTicketStore struct {
+	tickets *mongo.Collection
+}
+
+func NewMongoWatchPartyTicketStore(tickets *mongo.Collection) *MongoWatchPartyTicketStore {
+	return &MongoWatchPartyTicketStore{tickets: tickets}
+}
+
+func (s *MongoWatchPartyTicketStore) Insert(ctx context.Context, ticket models.WatchPartyTicket) error {
+	_, err := s.tickets.InsertOne(ctx, ticket)
+	return err
+}
+
+func (s *MongoWatchPartyTicketStore) Take(ctx context.Context, ticketHash, partyID string, now time.Time) (models.WatchPartyTicket, error) {
+	filter := bson.M{"ticket_hash": ticketHash, "party_id": partyID, "expires_at": bson.M{"$gt": now}}
+	var ticket models.WatchPartyTicket
+	err := s.tickets.FindOneAndDelete(ctx, filter).Decode(&ticket)
+	if err == mongo.ErrNoDocuments {
+		return ticket, ErrInvalidTicket
+	}
+	return ticket, err
+}
+
+// WatchPartyTicketService issues the single-use tickets watch party sockets
+// authenticate with, so no long-lived token ends up in a URL
+type WatchPartyTicketService struct {
+	store WatchPartyTicketStore
+	now   func() time.Time
+}
+
+func NewWatchPartyTicketService(store WatchPartyTicketStore) *WatchPartyTicketService {
+	return &WatchPartyTicketService{store: store, now: time.Now}
+}
+
+func hashTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a ticket for userID to join a party
+func (s *WatchPartyTicketService) Issue(ctx context.Context, partyID, userID string) (string, time.Time, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(secret)
+
+	record := models.WatchPartyTicket{
+		TicketHash: hashTicket(ticket),
+		PartyID:    partyID,
+		UserID:     userID,
+		ExpiresAt:  s.now().Add(watchPartyTicketTTL),
+	}
+	if err := s.store.Insert(ctx, record); err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, record.ExpiresAt, nil
+}
+
+// Redeem uses up a ticket for a party and returns whose it was
+func (s *WatchPartyTicketService) Redeem(ctx context.Context, partyID, ticket string) (string, error) {
+	if ticket == "" {
+		return "", ErrInvalidTicket
+	}
+
+	record, err := s.store.Take(ctx, hashTicket(ticket), partyID, s.now())
+	if err != nil {
+		return "", err
+	}
+	return record.UserID, nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultPlaybackURLTTL = 10 * time.Minute
+
+var (
+	ErrInvalidPlaybackSignature = errors.New("invalid playback signature")
+	ErrPlaybackURLExpired       = errors.New("playback URL has expired")
+)
+
+// playbackParams are the query parameters a signed playback URL carries
+var playbackParams = []string{"uid", "sid", "exp", "ipb", "sig"}
+
+// getPlaybackKey is the key playback URLs are signed with: PLAYBACK_SIGNING_KEY,
+// or the access token key when that isn't set
+func getPlaybackKey() []byte {
+	if key := os.Getenv("PLAYBACK_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	return []byte(getSecretKey())
+}
+
+// PlaybackURLTTL is how long playback URLs last, set in minutes by
+// PLAYBACK_URL_TTL_MINUTES. It is kept short so a leaked URL stops working
+// soon; each heartbeat hands the player a freshly signed query to use instead.
+func PlaybackURLTTL() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("PLAYBACK_URL_TTL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultPlaybackURLTTL
+}
+
+// PlaybackBindsIP reports whether playback URLs only work from the IP
+// address they were issued to, set by PLAYBACK_BIND_IP=true
+func PlaybackBindsIP() bool {
+	return os.Getenv("PLAYBACK_BIND_IP") == "true"
+}
+
+func playbackSignature(tmdbID int, userID, sessionID, expires, boundIP string) string {
+	mac := hmac.New(sha256.New, getPlaybackKey())
+	mac.Write([]byte("playback\n" + strconv.Itoa(tmdbID) + "\n" + userID + "\n" + sessionID + "\n" + expires + "\n" + boundIP))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignPlayback returns the query parameters that let userID play a title in
+// a playback session until expires. When clientIP is set the URL only works
+// from that address; the address itself stays out of the URL.
+func SignPlayback(tmdbID int, userID, sessionID, clientIP string, expires time.Time) url.Values {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{
+		"uid": {userID},
+		"sid": {sessionID},
+		"exp": {exp},
+		"sig": {playbackSignature(tmdbID, userID, sessionID, exp, clientIP)},
+	}
+	if clientIP != "" {
+		query.Set("ipb", "1")
+	}
+	return query
+}
+
+// VerifyPlayback checks the signed parameters of a playback URL for a title
+// requested from clientIP and returns the user and session it was issued to
+func VerifyPlayback(tmdbID int, query url.Values, clientIP string) (userID, sessionID string, err error) {
+	userID, sessionID, exp, sig := query.Get("uid"), query.Get("sid"), query.Get("exp"), query.Get("sig")
+	if userID == "" || sessionID == "" || exp == "" || sig == "" {
+		return "", "", ErrInvalidPlaybackSignature
+	}
+
+	boundIP := ""
+	if query.Get("ipb") == "1" {
+		boundIP = clientIP
+	}
+	if !hmac.Equal([]byte(sig), []byte(playbackSignature(tmdbID, userID, sessionID, exp, boundIP))) {
+		return "", "", ErrInvalidPlaybackSignature
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", "", ErrInvalidPlaybackSignature
+	}
+	if time.Now().Unix() > expires {
+		return "", "", ErrPlaybackURLExpired
+	}
+	return userID, sessionID, nil
+}
+
+// PlaybackQuery keeps only the signed playback parameters of a query, for
+// passing on to the URLs inside a playlist
+func PlaybackQuery(query url.Values) url.Values {
+	signed := url.Values{}
+	for _, param := range playbackParams {
+		if value := query.Get(param); value != "" {
+			signed.Set(param, value)
+		}
+	}
+	return signed
+}